@@ -6,7 +6,71 @@ const (
 	Resource_Type_PROJECT        = 2
 	Resource_Type_ITEM           = 3
 
+	MAX_USER_NAME_LENGTH = 64
+	MIN_PASSWORD_LENGTH  = 6
+	SESSION_TTL_HOURS    = 7 * 24
+	API_TOKEN_PREFIX     = "gdt_"
+	DEFAULT_TOKEN_DAYS   = 365
+	MAX_TOKEN_DAYS       = 3650
+	// token的最近使用时间只精确到分钟, 避免每次认证都写库
+	TOKEN_LAST_USED_INTERVAL_SECONDS = 60
+
+	Privilege_Type_NONE     = -1
 	Privilege_Type_PULLER   = 0
 	Privilege_Type_VIEWER   = 1
 	Privilege_Type_MODIFIER = 2
+
+	Gray_Status_RUNNING  = 0
+	Gray_Status_PROMOTED = 1
+	Gray_Status_ABORTED  = 2
+
+	DEFAULT_WATCH_TIMEOUT  = 30
+	MAX_WATCH_TIMEOUT      = 60
+	WATCH_RECHECK_INTERVAL = 5
+
+	DEFAULT_PAGE_SIZE = 20
+	MAX_PAGE_SIZE     = 100
+
+	// 去掉首尾相同的行后, 两侧剩余行数之积的上限, 用于限制LCS表的大小
+	MAX_DIFF_CELLS = 4000000
+
+	Audit_Action_CREATE           = "create"
+	Audit_Action_UPDATE           = "update"
+	Audit_Action_DELETE           = "delete"
+	Audit_Action_AUTHORIZE        = "authorize"
+	Audit_Action_DELETE_AUTHORIZE = "delete_authorize"
+	Audit_Action_SAVE_DRAFT       = "save_draft"
+	Audit_Action_DISCARD_DRAFT    = "discard_draft"
+	Audit_Action_PUBLISH          = "publish"
+	Audit_Action_ROLLBACK         = "rollback"
+	Audit_Action_START_GRAY       = "start_gray"
+	Audit_Action_WIDEN_GRAY       = "widen_gray"
+	Audit_Action_PROMOTE_GRAY     = "promote_gray"
+	Audit_Action_ABORT_GRAY       = "abort_gray"
+
+	Audit_Action_CREATE_ENVIRONMENT = "create_environment"
+	Audit_Action_DELETE_ENVIRONMENT = "delete_environment"
+	Audit_Action_SET_SCHEMA         = "set_schema"
+	MAX_ENVIRONMENT_NAME_LENGTH     = 32
+
+	Content_Type_TEXT       = "text"
+	Content_Type_JSON       = "json"
+	Content_Type_YAML       = "yaml"
+	Content_Type_TOML       = "toml"
+	Content_Type_INI        = "ini"
+	Content_Type_PROPERTIES = "properties"
+	SECRET_MASK             = "******"
+
+	DEFAULT_ROTATE_BATCH_SIZE = 100
+	MAX_ROTATE_BATCH_SIZE     = 1000
+	Rotate_Stage_ITEM         = "item"
+	Rotate_Stage_VERSION      = "version"
+	Rotate_Status_RUNNING     = "running"
+	Rotate_Status_DONE        = "done"
+	Rotate_Status_FAILED      = "failed"
+
+	ARCHIVE_FORMAT_VERSION    = 1
+	Import_Conflict_SKIP      = "skip"
+	Import_Conflict_OVERWRITE = "overwrite"
+	Import_Conflict_FAIL      = "fail"
 )
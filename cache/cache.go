@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+	"zoe/config"
+)
+
+const (
+	defaultTTL  = 60
+	defaultSize = 10000
+)
+
+type entry struct {
+	key       string
+	resName   string
+	value     interface{}
+	expiredAt time.Time
+}
+
+// generation在每次Invalidate时递增, 读库前取得的generation已过期时不再写入缓存,
+// 避免读库和写缓存之间发生的失效被旧内容覆盖
+type Cache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	size       int
+	generation uint64
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+var PullCache *Cache
+
+// 测试中替换以控制过期
+var now = time.Now
+
+func InitCache(c *config.Config) {
+	PullCache = NewCache(c.Cache.TTL, c.Cache.Size)
+}
+
+func NewCache(ttl, size int) *Cache {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	if size <= 0 {
+		size = defaultSize
+	}
+	return &Cache{
+		ttl:     time.Duration(ttl) * time.Second,
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func key(resName, userHash string) string {
+	return resName + "|" + userHash
+}
+
+func (c *Cache) Get(resName, userHash string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key(resName, userHash)]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*entry)
+	if now().After(e.expiredAt) {
+		c.removeElement(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return e.value, true
+}
+
+// 读库前调用, 把结果连同返回值一起交给Set
+func (c *Cache) Generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// generation之后发生过失效时放弃写入, 返回是否写入
+func (c *Cache) Set(resName, userHash string, value interface{}, generation uint64) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return false
+	}
+	k := key(resName, userHash)
+	if elem, ok := c.entries[k]; ok {
+		e := elem.Value.(*entry)
+		e.value = value
+		e.expiredAt = now().Add(c.ttl)
+		c.lru.MoveToFront(elem)
+		return true
+	}
+	c.entries[k] = c.lru.PushFront(&entry{
+		key:       k,
+		resName:   resName,
+		value:     value,
+		expiredAt: now().Add(c.ttl),
+	})
+	for c.lru.Len() > c.size {
+		c.removeElement(c.lru.Back())
+	}
+	return true
+}
+
+// 删除resName自身及其下级资源(以"resName."开头)的所有缓存
+func (c *Cache) Invalidate(resName string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for _, elem := range c.entries {
+		e := elem.Value.(*entry)
+		if e.resName == resName || strings.HasPrefix(e.resName, resName+".") {
+			c.removeElement(elem)
+		}
+	}
+}
+
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *Cache) removeElement(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*entry).key)
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheExpire(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+	cases := []struct {
+		name    string
+		elapsed time.Duration
+		hit     bool
+	}{
+		{"fresh", 0, true},
+		{"before ttl", 59 * time.Second, true},
+		{"at ttl", 60 * time.Second, true},
+		{"after ttl", 61 * time.Second, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			current = time.Now()
+			c := NewCache(60, 10)
+			c.Set("acme.web.db", "u1", "a=1", c.Generation())
+			current = current.Add(tc.elapsed)
+			if _, ok := c.Get("acme.web.db", "u1"); ok != tc.hit {
+				t.Errorf("Get() hit = %v, want %v", ok, tc.hit)
+			}
+		})
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	cases := []struct {
+		name       string
+		invalidate string
+		remaining  []string
+	}{
+		{"self", "foo", []string{"foobar", "foobar.baz", "bar.foo"}},
+		{"children", "foo.bar", []string{"foo", "foobar", "foobar.baz", "bar.foo"}},
+		{"same prefix is not a child", "foob", []string{"foo", "foo.bar", "foo.bar.baz", "foobar", "foobar.baz", "bar.foo"}},
+		{"sibling", "foobar", []string{"foo", "foo.bar", "foo.bar.baz", "bar.foo"}},
+	}
+	names := []string{"foo", "foo.bar", "foo.bar.baz", "foobar", "foobar.baz", "bar.foo"}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewCache(60, 100)
+			for _, name := range names {
+				c.Set(name, "u1", name, c.Generation())
+			}
+			c.Invalidate(tc.invalidate)
+			if c.Len() != len(tc.remaining) {
+				t.Errorf("Len() = %d, want %d", c.Len(), len(tc.remaining))
+			}
+			for _, name := range tc.remaining {
+				if _, ok := c.Get(name, "u1"); !ok {
+					t.Errorf("%s should not be invalidated", name)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheEvict(t *testing.T) {
+	c := NewCache(60, 2)
+	c.Set("a", "u1", 1, c.Generation())
+	c.Set("b", "u1", 2, c.Generation())
+	c.Get("a", "u1")
+	c.Set("c", "u1", 3, c.Generation())
+	cases := []struct {
+		name string
+		hit  bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tc := range cases {
+		if _, ok := c.Get(tc.name, "u1"); ok != tc.hit {
+			t.Errorf("Get(%s) hit = %v, want %v", tc.name, ok, tc.hit)
+		}
+	}
+}
+
+func TestCacheSetAfterInvalidate(t *testing.T) {
+	cases := []struct {
+		name       string
+		invalidate string
+		stored     bool
+	}{
+		{"no invalidation", "", true},
+		{"same resource", "acme.web.db", false},
+		// 世代是全局的, 任何失效都会放弃读库前发起的写入
+		{"other resource", "other", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewCache(60, 10)
+			generation := c.Generation()
+			if tc.invalidate != "" {
+				c.Invalidate(tc.invalidate)
+			}
+			if ok := c.Set("acme.web.db", "u1", "a=1", generation); ok != tc.stored {
+				t.Errorf("Set() = %v, want %v", ok, tc.stored)
+			}
+			if _, ok := c.Get("acme.web.db", "u1"); ok != tc.stored {
+				t.Errorf("Get() hit = %v, want %v", ok, tc.stored)
+			}
+		})
+	}
+}
@@ -13,7 +13,22 @@ type Config struct {
 	Database  struct {
 		Engine           string `yaml:"engine" binding:"required"`
 		ConnectionString string `yaml:"connectionstring" binding:"required"`
+		// 启动时自动执行未应用的结构迁移
+		AutoMigrate bool `yaml:"automigrate"`
 	} `yaml:"database"`
+	Cache struct {
+		TTL  int `yaml:"ttl"`
+		Size int `yaml:"size"`
+	} `yaml:"cache"`
+	// 加密item使用的密钥, keys为密钥id到base64编码的32字节AES密钥, 新写入的内容用keyid指定的密钥加密
+	Secret struct {
+		KeyId string            `yaml:"keyid"`
+		Keys  map[string]string `yaml:"keys"`
+	} `yaml:"secret"`
+	// 可以执行密钥轮换等全局管理操作的用户名
+	Admins []string `yaml:"admins"`
+	// 可信的反向代理地址或ip段, 只有来自这些地址的请求才采信X-Forwarded-For
+	TrustedProxies []string `yaml:"trustedproxies"`
 }
 
 var C *Config
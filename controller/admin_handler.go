@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"zoe/middleware"
+	"zoe/model"
+	"zoe/service"
+)
+
+func StartKeyRotationHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var req model.RotateKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.StartKeyRotation(user, req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func KeyRotationHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	result, err := service.GetKeyRotation(user)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
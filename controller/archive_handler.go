@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"zoe/middleware"
+	"zoe/model"
+	"zoe/service"
+)
+
+func ExportOrgHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var query model.ExportOrgQuery
+	if err := c.ShouldBindQuery(&query); err != nil || (query.Format != "" && query.Format != "json" && query.Format != "yaml") {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	orgId, _ := strconv.Atoi(c.Param("org_id"))
+	archive, err := service.ExportOrg(user, orgId, query.Privileges)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	if query.Format == "yaml" {
+		data, err := yaml.Marshal(archive)
+		if err != nil {
+			_ = seelog.Critical(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "msg": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename="+archive.Name+".yaml")
+		c.Data(http.StatusOK, "application/x-yaml; charset=utf-8", data)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+archive.Name+".json")
+	c.JSON(http.StatusOK, archive)
+}
+
+// 请求体为导出的文件, format未指定时按Content-Type判断是否为yaml
+func ImportOrgHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var query model.ImportOrgQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	var archive model.OrgArchive
+	if query.Format == "yaml" || (query.Format == "" && strings.Contains(c.ContentType(), "yaml")) {
+		err = yaml.Unmarshal(data, &archive)
+	} else {
+		err = json.Unmarshal(data, &archive)
+	}
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "导入文件格式错误: " + err.Error()})
+		return
+	}
+	result, err := service.ImportOrg(user, query, &archive)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
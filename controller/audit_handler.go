@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"zoe/middleware"
+	"zoe/model"
+	"zoe/service"
+)
+
+func ListAuditHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var query model.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.ListAudit(user, query)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"zoe/middleware"
+	"zoe/model"
+	"zoe/service"
+)
+
+func CreateEnvironmentHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	var req model.CreateEnvironmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.CreateEnvironment(user, projectId, req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func ListEnvironmentHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	result, err := service.ListEnvironment(user, projectId)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func DeleteEnvironmentHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	result, err := service.DeleteEnvironment(user, projectId, c.Param("name"))
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"zoe/middleware"
+	"zoe/model"
+	"zoe/service"
+)
+
+func StartGrayHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var req model.StartGrayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	result, err := service.StartGray(user, itemId, req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, contentErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func WidenGrayHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var req model.UpdateGrayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	result, err := service.WidenGray(user, itemId, req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func PromoteGrayHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	result, err := service.PromoteGray(user, itemId)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func AbortGrayHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	result, err := service.AbortGray(user, itemId)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"zoe/middleware"
+	"zoe/model"
+	"zoe/service"
+	"zoe/utils"
+)
+
+// 内容格式错误时额外返回出错的行列号, 不符合schema时返回全部违反项
+func contentErrorResponse(err error) gin.H {
+	result := gin.H{"code": -1, "msg": err.Error()}
+	if contentErr, ok := err.(*utils.ContentError); ok {
+		result["data"] = gin.H{
+			"content_type": contentErr.ContentType,
+			"line":         contentErr.Line,
+			"column":       contentErr.Column,
+			"message":      contentErr.Message,
+		}
+	} else if schemaErr, ok := err.(*utils.SchemaError); ok {
+		result["data"] = gin.H{"violations": schemaErr.Violations}
+	}
+	return result
+}
+
+func CreateItemHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var req model.CreateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.CreateItem(user, req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, contentErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func UpdateItemHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var req model.UpdateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	result, err := service.UpdateItem(user, itemId, req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, contentErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func DeleteItemHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	result, err := service.DeleteItem(user, itemId)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func SingleItemHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	result, err := service.SingleItem(user, itemId)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func RollbackItemHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var req model.RollbackItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	result, err := service.RollbackItem(user, itemId, req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, contentErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func SaveDraftHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var req model.SaveDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	result, err := service.SaveDraft(user, itemId, req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, contentErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func DiscardDraftHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	result, err := service.DiscardDraft(user, itemId)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func PublishItemHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	result, err := service.PublishItem(user, itemId)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, contentErrorResponse(err))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func AuthorizeItemHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	var req model.AuthorizeItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.AuthorizeItem(user, itemId, req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func DeleteAuthorizeItemHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	userId, _ := strconv.Atoi(c.Param("user_id"))
+	result, err := service.DeleteAuthorizeItem(user, itemId, userId)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusForbidden, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func ListAuthorizeItemHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	var query model.ListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.ListAuthorizeItem(user, itemId, query)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusForbidden, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
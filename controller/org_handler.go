@@ -5,23 +5,20 @@ import (
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"zoe/middleware"
 	"zoe/model"
 	"zoe/service"
 )
 
 func CreateOrgHandler(c *gin.Context) {
-	userHash, err := c.Cookie("user_hash")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
-		return
-	}
+	user := middleware.CurrentUser(c)
 	var req model.OrgCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
 		return
 	}
-	result, err := service.CreateOrg(userHash, req)
+	result, err := service.CreateOrg(user, req)
 	if err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
@@ -31,11 +28,7 @@ func CreateOrgHandler(c *gin.Context) {
 }
 
 func UpdateOrgHandler(c *gin.Context) {
-	userHash, err := c.Cookie("user_hash")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
-		return
-	}
+	user := middleware.CurrentUser(c)
 	var req model.OrgUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		_ = seelog.Critical(err.Error())
@@ -43,7 +36,7 @@ func UpdateOrgHandler(c *gin.Context) {
 		return
 	}
 	orgId, _ := strconv.Atoi(c.Param("org_id"))
-	result, err := service.UpdateOrg(userHash, orgId, req)
+	result, err := service.UpdateOrg(user, orgId, req)
 	if err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
@@ -53,13 +46,9 @@ func UpdateOrgHandler(c *gin.Context) {
 }
 
 func DeleteOrgHandler(c *gin.Context) {
-	userHash, err := c.Cookie("user_hash")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
-		return
-	}
+	user := middleware.CurrentUser(c)
 	orgId, _ := strconv.Atoi(c.Param("org_id"))
-	result, err := service.DeleteOrg(userHash, orgId)
+	result, err := service.DeleteOrg(user, orgId)
 	if err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
@@ -69,12 +58,14 @@ func DeleteOrgHandler(c *gin.Context) {
 }
 
 func ListOrgHandler(c *gin.Context) {
-	userHash, err := c.Cookie("user_hash")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+	user := middleware.CurrentUser(c)
+	var query model.ListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
 		return
 	}
-	result, err := service.ListOrg(userHash)
+	result, err := service.ListOrg(user, query)
 	if err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
@@ -84,13 +75,15 @@ func ListOrgHandler(c *gin.Context) {
 }
 
 func SingleOrgHandler(c *gin.Context) {
-	userHash, err := c.Cookie("user_hash")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+	user := middleware.CurrentUser(c)
+	orgId, _ := strconv.Atoi(c.Param("org_id"))
+	var query model.ListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
 		return
 	}
-	orgId, _ := strconv.Atoi(c.Param("org_id"))
-	result, err := service.SingleOrg(userHash, orgId)
+	result, err := service.SingleOrg(user, orgId, query)
 	if err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
@@ -99,12 +92,26 @@ func SingleOrgHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-func AuthorizeOrgHandler(c *gin.Context) {
-	userHash, err := c.Cookie("user_hash")
+func ListAuthorizeOrgHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	orgId, _ := strconv.Atoi(c.Param("org_id"))
+	var query model.ListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.ListAuthorizeOrg(user, orgId, query)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusForbidden, gin.H{"code": -1, "msg": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, result)
+}
+
+func AuthorizeOrgHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
 	orgId, _ := strconv.Atoi(c.Param("org_id"))
 	var req model.AuthorizeOrgRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -112,7 +119,7 @@ func AuthorizeOrgHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
 		return
 	}
-	result, err := service.AuthorizeOrg(userHash, orgId, req)
+	result, err := service.AuthorizeOrg(user, orgId, req)
 	if err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
@@ -122,14 +129,10 @@ func AuthorizeOrgHandler(c *gin.Context) {
 }
 
 func DeleteAuthorizeOrgHandler(c *gin.Context) {
-	userHash, err := c.Cookie("user_hash")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
-		return
-	}
+	user := middleware.CurrentUser(c)
 	orgId, _ := strconv.Atoi(c.Param("org_id"))
 	userId, _ := strconv.Atoi(c.Param("user_id"))
-	result, err := service.DeleteAuthorizeOrg(userHash, orgId, userId)
+	result, err := service.DeleteAuthorizeOrg(user, orgId, userId, c.Query("environment"))
 	if err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusForbidden, gin.H{"code": -1, "msg": err.Error()})
@@ -5,24 +5,38 @@ import (
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"zoe/middleware"
 	"zoe/model"
 	"zoe/service"
 )
 
 func CreateProjectHandler(c *gin.Context) {
-	userHash, err := c.Cookie("user_hash")
+	user := middleware.CurrentUser(c)
+	var req model.CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.CreateProject(user, req)
 	if err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
 		return
 	}
-	var req model.CreateProjectRequest
+	c.JSON(http.StatusOK, result)
+}
+
+func UpdateProjectHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var req model.UpdateProjectRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
 		return
 	}
-	result, err := service.CreateProject(userHash, req)
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	result, err := service.UpdateProject(user, projectId, req)
 	if err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
@@ -31,20 +45,40 @@ func CreateProjectHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-func UpdateProjectHandler(c *gin.Context) {
-	userHash, err := c.Cookie("user_hash")
+func SingleProjectHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	result, err := service.SingleProject(user, projectId)
 	if err != nil {
+		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
 		return
 	}
-	var req model.UpdateProjectRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	c.JSON(http.StatusOK, result)
+}
+
+func ListProjectHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	orgId, _ := strconv.Atoi(c.Param("org_id"))
+	var query model.ListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
 		return
 	}
+	result, err := service.ListProject(user, orgId, query)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func DeleteProjectHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
 	projectId, _ := strconv.Atoi(c.Param("project_id"))
-	result, err := service.UpdateProject(userHash, projectId, req)
+	result, err := service.DeleteProject(user, projectId)
 	if err != nil {
 		_ = seelog.Critical(err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
@@ -52,3 +86,52 @@ func UpdateProjectHandler(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, result)
 }
+
+func AuthorizeProjectHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	var req model.AuthorizeProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.AuthorizeProject(user, projectId, req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func DeleteAuthorizeProjectHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	userId, _ := strconv.Atoi(c.Param("user_id"))
+	result, err := service.DeleteAuthorizeProject(user, projectId, userId, c.Query("environment"))
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusForbidden, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func ListAuthorizeProjectHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	var query model.ListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.ListAuthorizeProject(user, projectId, query)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusForbidden, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
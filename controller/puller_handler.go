@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"errors"
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"zoe/middleware"
+	"zoe/model"
+	"zoe/service"
+)
+
+func getClientId(c *gin.Context) string {
+	if clientId := c.GetHeader("X-Guldan-Client-Id"); clientId != "" {
+		return clientId
+	}
+	return c.Query("client_id")
+}
+
+func getEnvironment(c *gin.Context) string {
+	if environment := c.GetHeader("X-Guldan-Environment"); environment != "" {
+		return environment
+	}
+	return c.Query("environment")
+}
+
+func PullHandler(c *gin.Context) {
+	result, err := service.Pull(middleware.CurrentUser(c), getClientId(c), middleware.ClientIp(c), getEnvironment(c), c.Query("format"), c.Param("org"), c.Param("project"), c.Param("item"))
+	if err != nil {
+		if err == service.ErrPullNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"code": -1, "msg": err.Error()})
+		} else if err == service.ErrPullForbidden {
+			c.JSON(http.StatusForbidden, gin.H{"code": -1, "msg": err.Error()})
+		} else if errors.Is(err, service.ErrPullFormat) {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		} else {
+			_ = seelog.Critical(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "msg": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func WatchHandler(c *gin.Context) {
+	var req model.WatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.Watch(c.Request.Context(), middleware.CurrentUser(c), getClientId(c), middleware.ClientIp(c), getEnvironment(c), req)
+	if err != nil {
+		if err == service.ErrPullNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"code": -1, "msg": err.Error()})
+		} else if err == service.ErrPullForbidden {
+			c.JSON(http.StatusForbidden, gin.H{"code": -1, "msg": err.Error()})
+		} else if err == c.Request.Context().Err() {
+			return
+		} else {
+			_ = seelog.Critical(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "msg": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"zoe/basic"
+	"zoe/middleware"
+	"zoe/model"
+	"zoe/service"
+)
+
+func setSchema(c *gin.Context, resId, resType int) {
+	user := middleware.CurrentUser(c)
+	var req model.SetSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.SetSchema(user, resId, resType, string(req.Schema))
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func deleteSchema(c *gin.Context, resId, resType int) {
+	user := middleware.CurrentUser(c)
+	result, err := service.SetSchema(user, resId, resType, "")
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func listSchema(c *gin.Context, resId, resType int) {
+	user := middleware.CurrentUser(c)
+	result, err := service.ListSchema(user, resId, resType)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func SetProjectSchemaHandler(c *gin.Context) {
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	setSchema(c, projectId, basic.Resource_Type_PROJECT)
+}
+
+func DeleteProjectSchemaHandler(c *gin.Context) {
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	deleteSchema(c, projectId, basic.Resource_Type_PROJECT)
+}
+
+func ListProjectSchemaHandler(c *gin.Context) {
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	listSchema(c, projectId, basic.Resource_Type_PROJECT)
+}
+
+func SetItemSchemaHandler(c *gin.Context) {
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	setSchema(c, itemId, basic.Resource_Type_ITEM)
+}
+
+func DeleteItemSchemaHandler(c *gin.Context) {
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	deleteSchema(c, itemId, basic.Resource_Type_ITEM)
+}
+
+func ListItemSchemaHandler(c *gin.Context) {
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	listSchema(c, itemId, basic.Resource_Type_ITEM)
+}
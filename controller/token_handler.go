@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"zoe/middleware"
+	"zoe/model"
+	"zoe/service"
+)
+
+func CreateTokenHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	var req model.CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.CreateToken(user, req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func ListTokenHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	result, err := service.ListToken(user)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func DeleteTokenHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	tokenId, _ := strconv.Atoi(c.Param("token_id"))
+	result, err := service.DeleteToken(user, tokenId)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
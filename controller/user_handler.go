@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"zoe/basic"
+	"zoe/middleware"
+	"zoe/model"
+	"zoe/service"
+)
+
+func RegisterHandler(c *gin.Context) {
+	var req model.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.Register(req)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func LoginHandler(c *gin.Context) {
+	var req model.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, token, err := service.Login(req)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.SetCookie(middleware.SessionCookieName, token, basic.SESSION_TTL_HOURS*3600, "/", "", false, true)
+	c.JSON(http.StatusOK, result)
+}
+
+func LogoutHandler(c *gin.Context) {
+	token := middleware.GetSessionToken(c)
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "当前请求未使用会话登录"})
+		return
+	}
+	result, err := service.Logout(token)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.SetCookie(middleware.SessionCookieName, "", -1, "/", "", false, true)
+	c.JSON(http.StatusOK, result)
+}
+
+func CurrentUserHandler(c *gin.Context) {
+	result, err := service.CurrentUser(middleware.CurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
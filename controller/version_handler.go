@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"zoe/basic"
+	"zoe/middleware"
+	"zoe/service"
+)
+
+func listVersion(c *gin.Context, resId, resType int) {
+	user := middleware.CurrentUser(c)
+	result, err := service.ListVersion(user, resId, resType)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func ListOrgVersionHandler(c *gin.Context) {
+	orgId, _ := strconv.Atoi(c.Param("org_id"))
+	listVersion(c, orgId, basic.Resource_Type_ORG)
+}
+
+func ListProjectVersionHandler(c *gin.Context) {
+	projectId, _ := strconv.Atoi(c.Param("project_id"))
+	listVersion(c, projectId, basic.Resource_Type_PROJECT)
+}
+
+func ListItemVersionHandler(c *gin.Context) {
+	itemId, _ := strconv.Atoi(c.Param("item_id"))
+	listVersion(c, itemId, basic.Resource_Type_ITEM)
+}
+
+func SingleVersionHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	versionId, _ := strconv.Atoi(c.Param("version_id"))
+	result, err := service.SingleVersion(user, versionId)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func DiffVersionHandler(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	versionId, _ := strconv.Atoi(c.Param("version_id"))
+	baseVersionId, err := strconv.Atoi(c.Query("base"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": "参数错误"})
+		return
+	}
+	result, err := service.DiffVersion(user, versionId, baseVersionId)
+	if err != nil {
+		_ = seelog.Critical(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
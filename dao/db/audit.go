@@ -0,0 +1,90 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+	"zoe/model"
+)
+
+type auditRepo struct {
+	conn *sql.Tx
+}
+
+func (r auditRepo) query(sql string, args ...interface{}) (*[]model.Audit, error) {
+	var audits []model.Audit
+	rows, err := r.conn.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	var audit model.Audit
+	for rows.Next() {
+		err = rows.Scan(&audit.Id, &audit.OrgId, &audit.UserId, &audit.Action, &audit.ResourceType, &audit.ResourceId,
+			&audit.ResourceName, &audit.TargetUserId, &audit.Before, &audit.After, &audit.CreateAt)
+		if err != nil {
+			return nil, err
+		}
+		audits = append(audits, audit)
+	}
+	return &audits, nil
+}
+
+func (r auditRepo) Create(audit *model.Audit) (int, error) {
+	sql := "insert into audit (org_id, user_id, action, resource_type, resource_id, resource_name, target_user_id, before_value, after_value) " +
+		"values(?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := r.conn.Exec(sql, audit.OrgId, audit.UserId, audit.Action, audit.ResourceType, audit.ResourceId,
+		audit.ResourceName, audit.TargetUserId, audit.Before, audit.After)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r auditRepo) List(query model.AuditQuery, resType, offset, limit int) (*[]model.Audit, int, error) {
+	conditions := []string{"org_id = ?"}
+	args := []interface{}{query.OrgId}
+	if query.UserId != 0 {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, query.UserId)
+	}
+	if query.TargetUserId != 0 {
+		conditions = append(conditions, "target_user_id = ?")
+		args = append(args, query.TargetUserId)
+	}
+	if query.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, query.Action)
+	}
+	if resType != 0 {
+		conditions = append(conditions, "resource_type = ?")
+		args = append(args, resType)
+	}
+	if query.ResourceId != 0 {
+		conditions = append(conditions, "resource_id = ?")
+		args = append(args, query.ResourceId)
+	}
+	if query.Since != 0 {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, utc(time.Unix(query.Since, 0)))
+	}
+	if query.Until != 0 {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, utc(time.Unix(query.Until, 0)))
+	}
+	where := strings.Join(conditions, " and ")
+	var total int
+	err := r.conn.QueryRow("select count(*) from audit where "+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	audits, err := r.query("select * from audit where "+where+" order by id desc limit ? offset ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return audits, total, nil
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"database/sql"
+	"zoe/model"
+)
+
+type schemaRepo struct {
+	conn *sql.Tx
+}
+
+func (r schemaRepo) query(sql string, args ...interface{}) (*[]model.Schema, error) {
+	var schemas []model.Schema
+	rows, err := r.conn.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	var schema model.Schema
+	for rows.Next() {
+		err = rows.Scan(&schema.Id, &schema.ResourceId, &schema.ResourceType, &schema.Version, &schema.Content,
+			&schema.UserId, &schema.CreateAt)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return &schemas, nil
+}
+
+func (r schemaRepo) GetLatest(resId, resType int) (*model.Schema, error) {
+	schemas, err := r.query("select * from content_schema where resource_id = ? and resource_type = ? order by version desc limit 1",
+		resId, resType)
+	if err != nil {
+		return nil, err
+	}
+	if len(*schemas) > 0 {
+		return &(*schemas)[0], nil
+	}
+	return nil, nil
+}
+
+func (r schemaRepo) ListByResource(resId, resType int) (*[]model.Schema, error) {
+	return r.query("select * from content_schema where resource_id = ? and resource_type = ? order by version desc", resId, resType)
+}
+
+func (r schemaRepo) Create(resId, resType, version int, content string, userId int) (int, error) {
+	sql := "insert into content_schema (resource_id, resource_type, version, content, user_id) values(?, ?, ?, ?, ?)"
+	res, err := r.conn.Exec(sql, resId, resType, version, content, userId)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
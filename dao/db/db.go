@@ -1,26 +1,111 @@
 package db
 
 import (
+	"database/sql"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	"time"
 	"zoe/config"
+	"zoe/dao/repo"
 )
 
-var DB *sqlx.DB
+func init() {
+	repo.Register("mysql", InitMysql)
+}
+
+type store struct {
+	db      *sqlx.DB
+	dialect string
+}
 
-func InitMysql(c *config.Config) error {
+func InitMysql(c *config.Config) (repo.Store, error) {
 	database, err := sqlx.Open("mysql", c.Database.ConnectionString)
 	if err != nil {
 		fmt.Println("init mysql error: ", err)
-		return err
+		return nil, err
 	}
-	DB = database
-	return nil
+	return &store{db: database, dialect: dialectMysql}, nil
+}
+
+// 时间参数统一按UTC传入: sqlite以文本保存时间并按字符串比较, 需要与默认值current_timestamp一致;
+// mysql驱动会按连接的loc转换, 不受影响
+func utc(t time.Time) time.Time {
+	return t.UTC()
 }
 
-func Destroy() {
-	if DB != nil {
-		_ = DB.Close()
+func (s *store) Begin() (repo.Tx, error) {
+	conn, err := s.db.Begin()
+	if err != nil {
+		return nil, err
 	}
+	return &tx{conn: conn}, nil
+}
+
+func (s *store) Close() error {
+	return s.db.Close()
+}
+
+type tx struct {
+	conn *sql.Tx
+}
+
+func (t *tx) Commit() error {
+	return t.conn.Commit()
+}
+
+func (t *tx) Rollback() error {
+	return t.conn.Rollback()
+}
+
+func (t *tx) Orgs() repo.OrgRepository {
+	return orgRepo{conn: t.conn}
+}
+
+func (t *tx) Projects() repo.ProjectRepository {
+	return projectRepo{conn: t.conn}
+}
+
+func (t *tx) Items() repo.ItemRepository {
+	return itemRepo{conn: t.conn}
+}
+
+func (t *tx) Versions() repo.VersionRepository {
+	return versionRepo{conn: t.conn}
+}
+
+func (t *tx) Drafts() repo.DraftRepository {
+	return draftRepo{conn: t.conn}
+}
+
+func (t *tx) Grays() repo.GrayRepository {
+	return grayRepo{conn: t.conn}
+}
+
+func (t *tx) Privileges() repo.PrivilegeRepository {
+	return privilegeRepo{conn: t.conn}
+}
+
+func (t *tx) Environments() repo.EnvironmentRepository {
+	return environmentRepo{conn: t.conn}
+}
+
+func (t *tx) Schemas() repo.SchemaRepository {
+	return schemaRepo{conn: t.conn}
+}
+
+func (t *tx) Users() repo.UserRepository {
+	return userRepo{conn: t.conn}
+}
+
+func (t *tx) Sessions() repo.SessionRepository {
+	return sessionRepo{conn: t.conn}
+}
+
+func (t *tx) Tokens() repo.TokenRepository {
+	return tokenRepo{conn: t.conn}
+}
+
+func (t *tx) Audits() repo.AuditRepository {
+	return auditRepo{conn: t.conn}
 }
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"zoe/model"
+)
+
+type draftRepo struct {
+	conn *sql.Tx
+}
+
+func (r draftRepo) GetByItemId(itemId int) (*model.Draft, error) {
+	var drafts []model.Draft
+	rows, err := r.conn.Query("select * from draft where item_id = ? and is_deleted = 0", itemId)
+	if err != nil {
+		return nil, err
+	}
+	var draft model.Draft
+	for rows.Next() {
+		err = rows.Scan(&draft.Id, &draft.ItemId, &draft.Content, &draft.UserId, &draft.IsDeleted, &draft.UpdatedAt, &draft.CreateAt)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, draft)
+	}
+	if len(drafts) > 0 {
+		return &drafts[0], nil
+	}
+	return nil, nil
+}
+
+func (r draftRepo) Save(itemId int, content string, userId int) error {
+	draft, err := r.GetByItemId(itemId)
+	if err != nil {
+		return err
+	}
+	if draft != nil {
+		sql := "update draft set content = ?, user_id = ? where id = ?"
+		_, err = r.conn.Exec(sql, content, userId, draft.Id)
+	} else {
+		sql := "insert into draft (item_id, content, user_id) values(?, ?, ?)"
+		_, err = r.conn.Exec(sql, itemId, content, userId)
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r draftRepo) Delete(itemId int) error {
+	sql := "update draft set is_deleted = 1 where item_id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, itemId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r draftRepo) ReplaceContent(itemId int, oldContent, newContent string) (bool, error) {
+	sql := "update draft set content = ? where item_id = ? and content = ? and is_deleted = 0"
+	res, err := r.conn.Exec(sql, newContent, itemId, oldContent)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"database/sql"
+	"zoe/model"
+)
+
+type environmentRepo struct {
+	conn *sql.Tx
+}
+
+func (r environmentRepo) query(sql string, args ...interface{}) (*[]model.Environment, error) {
+	var environments []model.Environment
+	rows, err := r.conn.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	var environment model.Environment
+	for rows.Next() {
+		err = rows.Scan(&environment.Id, &environment.ProjectId, &environment.Name, &environment.IsDeleted,
+			&environment.UpdatedAt, &environment.CreateAt)
+		if err != nil {
+			return nil, err
+		}
+		environments = append(environments, environment)
+	}
+	return &environments, nil
+}
+
+func (r environmentRepo) GetByProjectIdAndName(projectId int, name string) (*model.Environment, error) {
+	environments, err := r.query("select * from environment where project_id = ? and name = ? and is_deleted = 0", projectId, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(*environments) > 0 {
+		return &(*environments)[0], nil
+	}
+	return nil, nil
+}
+
+func (r environmentRepo) ListByProjectId(projectId int) (*[]model.Environment, error) {
+	return r.query("select * from environment where project_id = ? and is_deleted = 0 order by id", projectId)
+}
+
+func (r environmentRepo) Create(projectId int, name string) (int, error) {
+	sql := "insert into environment (project_id, name) values(?, ?)"
+	res, err := r.conn.Exec(sql, projectId, name)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r environmentRepo) Delete(id int) error {
+	sql := "update environment set is_deleted = 1 where id = ?"
+	_, err := r.conn.Exec(sql, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r environmentRepo) DeleteByProjectId(projectId int) error {
+	sql := "update environment set is_deleted = 1 where project_id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, projectId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
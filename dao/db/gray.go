@@ -0,0 +1,73 @@
+package db
+
+import (
+	"database/sql"
+	"zoe/basic"
+	"zoe/model"
+)
+
+type grayRepo struct {
+	conn *sql.Tx
+}
+
+func (r grayRepo) GetRunningByItemId(itemId int) (*model.GrayRelease, error) {
+	var grays []model.GrayRelease
+	sql := "select * from gray_release where item_id = ? and status = ? and is_deleted = 0"
+	rows, err := r.conn.Query(sql, itemId, basic.Gray_Status_RUNNING)
+	if err != nil {
+		return nil, err
+	}
+	var gray model.GrayRelease
+	for rows.Next() {
+		err = rows.Scan(&gray.Id, &gray.ItemId, &gray.VersionId, &gray.ClientIds, &gray.IpRanges, &gray.Percentage,
+			&gray.Status, &gray.UserId, &gray.IsDeleted, &gray.UpdatedAt, &gray.CreateAt)
+		if err != nil {
+			return nil, err
+		}
+		grays = append(grays, gray)
+	}
+	if len(grays) > 0 {
+		return &grays[0], nil
+	}
+	return nil, nil
+}
+
+func (r grayRepo) Create(itemId, versionId int, clientIds, ipRanges string, percentage, userId int) (int, error) {
+	sql := "insert into gray_release (item_id, version_id, client_ids, ip_ranges, percentage, status, user_id) values(?, ?, ?, ?, ?, ?, ?)"
+	res, err := r.conn.Exec(sql, itemId, versionId, clientIds, ipRanges, percentage, basic.Gray_Status_RUNNING, userId)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r grayRepo) UpdateRule(id int, clientIds, ipRanges string, percentage int) error {
+	sql := "update gray_release set client_ids = ?, ip_ranges = ?, percentage = ? where id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, clientIds, ipRanges, percentage, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r grayRepo) UpdateStatus(id, status int) error {
+	sql := "update gray_release set status = ? where id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, status, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r grayRepo) DeleteByItemId(itemId int) error {
+	sql := "update gray_release set is_deleted = 1 where item_id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, itemId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
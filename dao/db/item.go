@@ -0,0 +1,148 @@
+package db
+
+import (
+	"database/sql"
+	"zoe/model"
+)
+
+type itemRepo struct {
+	conn *sql.Tx
+}
+
+func (r itemRepo) query(sql string, args ...interface{}) (*[]model.Item, error) {
+	var items []model.Item
+	rows, err := r.conn.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	var item model.Item
+	for rows.Next() {
+		err = rows.Scan(&item.Id, &item.Name, &item.ParentId, &item.Visibility, &item.Content, &item.CurrentVersionId,
+			&item.IsDeleted, &item.UpdatedAt, &item.CreateAt, &item.Environment, &item.ContentType, &item.Encrypted)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return &items, nil
+}
+
+func (r itemRepo) queryOne(sql string, args ...interface{}) (*model.Item, error) {
+	items, err := r.query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(*items) > 0 {
+		return &(*items)[0], nil
+	}
+	return nil, nil
+}
+
+func (r itemRepo) GetById(id int) (*model.Item, error) {
+	return r.queryOne("select * from item where id = ? and is_deleted = 0", id)
+}
+
+func (r itemRepo) GetByParentIdAndName(parentId int, name, environment string) (*model.Item, error) {
+	return r.queryOne("select * from item where name = ? and parent_id = ? and environment = ? and is_deleted = 0", name, parentId, environment)
+}
+
+func (r itemRepo) ListByParentId(projectId int) (*[]model.Item, error) {
+	return r.query("select * from item where parent_id = ? and is_deleted = 0", projectId)
+}
+
+func (r itemRepo) ListByParentIdAndEnvironment(projectId int, environment string) (*[]model.Item, error) {
+	return r.query("select * from item where parent_id = ? and environment = ? and is_deleted = 0", projectId, environment)
+}
+
+func (r itemRepo) Create(name, content, environment, contentType string, visibility, encrypted, parentId int) (int, error) {
+	sql := "insert into item (name, parent_id, visibility, content, environment, content_type, encrypted) values(?, ?, ?, ?, ?, ?, ?)"
+	res, err := r.conn.Exec(sql, name, parentId, visibility, content, environment, contentType, encrypted)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r itemRepo) UpdateVisibility(id, visibility int) error {
+	sql := "update item set visibility = ? where id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, visibility, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r itemRepo) UpdateContentType(id int, contentType string) error {
+	sql := "update item set content_type = ? where id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, contentType, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r itemRepo) UpdateEncrypted(id, encrypted int) error {
+	sql := "update item set encrypted = ? where id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, encrypted, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r itemRepo) UpdateContent(id int, content string) error {
+	sql := "update item set content = ? where id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, content, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r itemRepo) UpdateCurrentVersionId(id, versionId int) error {
+	sql := "update item set current_version_id = ? where id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, versionId, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r itemRepo) Delete(id int) error {
+	sql := "update item set is_deleted = 1 where id = ?"
+	_, err := r.conn.Exec(sql, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r itemRepo) ListEncrypted(afterId, limit int) (*[]model.Item, int, error) {
+	var total int
+	err := r.conn.QueryRow("select count(*) from item where encrypted = 1").Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	items, err := r.query("select * from item where encrypted = 1 and id > ? order by id limit ?", afterId, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (r itemRepo) ReplaceContent(id int, oldContent, newContent string) (bool, error) {
+	sql := "update item set content = ? where id = ? and content = ?"
+	res, err := r.conn.Exec(sql, newContent, id, oldContent)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"fmt"
+	"zoe/dao/repo"
+)
+
+const (
+	dialectMysql  = "mysql"
+	dialectSqlite = "sqlite"
+)
+
+type migration struct {
+	version int
+	name    string
+	// 按方言分别给出语句, mysql驱动默认不允许一次执行多条语句; down为nil的迁移不可回退
+	up   map[string][]string
+	down map[string][]string
+}
+
+// 新的表结构变更只能追加到末尾, 已发布的迁移不能再修改
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "init",
+		// 迁移1接管已有的表, 不可回退, 否则会删掉并非由它创建的数据
+		up: map[string][]string{dialectMysql: mysqlInitUp, dialectSqlite: sqliteInitUp},
+	},
+	{
+		version: 2,
+		name:    "environment",
+		up:      map[string][]string{dialectMysql: mysqlEnvironmentUp, dialectSqlite: sqliteEnvironmentUp},
+		down:    map[string][]string{dialectMysql: mysqlEnvironmentDown, dialectSqlite: sqliteEnvironmentDown},
+	},
+	{
+		version: 3,
+		name:    "content_type",
+		up:      map[string][]string{dialectMysql: contentTypeUp, dialectSqlite: contentTypeUp},
+		down:    map[string][]string{dialectMysql: mysqlContentTypeDown, dialectSqlite: sqliteContentTypeDown},
+	},
+	{
+		version: 4,
+		name:    "content_schema",
+		up:      map[string][]string{dialectMysql: mysqlContentSchemaUp, dialectSqlite: sqliteContentSchemaUp},
+		down:    map[string][]string{dialectMysql: contentSchemaDown, dialectSqlite: contentSchemaDown},
+	},
+	{
+		version: 5,
+		name:    "item_encrypted",
+		up:      map[string][]string{dialectMysql: itemEncryptedUp, dialectSqlite: itemEncryptedUp},
+		down:    map[string][]string{dialectMysql: mysqlItemEncryptedDown, dialectSqlite: sqliteItemEncryptedDown},
+	},
+	{
+		version: 6,
+		name:    "version_encrypted",
+		up:      map[string][]string{dialectMysql: versionEncryptedUp, dialectSqlite: versionEncryptedUp},
+		down:    map[string][]string{dialectMysql: mysqlVersionEncryptedDown, dialectSqlite: sqliteVersionEncryptedDown},
+	},
+	{
+		version: 7,
+		name:    "org_visibility",
+		up:      map[string][]string{dialectMysql: orgVisibilityUp, dialectSqlite: orgVisibilityUp},
+		down:    map[string][]string{dialectMysql: {}, dialectSqlite: {}},
+	},
+}
+
+const schemaVersionTable = "create table if not exists schema_version (" +
+	"version integer not null primary key, " +
+	"name varchar(255) not null, " +
+	"applied_at datetime not null default current_timestamp)"
+
+func (s *store) Migrations() []repo.Migration {
+	result := make([]repo.Migration, len(migrations))
+	for index, m := range migrations {
+		result[index] = repo.Migration{Version: m.version, Name: m.name}
+	}
+	return result
+}
+
+func (s *store) SchemaVersion() (int, error) {
+	if _, err := s.db.Exec(schemaVersionTable); err != nil {
+		return 0, err
+	}
+	var version int
+	err := s.db.QueryRow("select coalesce(max(version), 0) from schema_version").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *store) MigrateTo(version int, progress func(m repo.Migration, up bool)) error {
+	if version < 0 || version > migrations[len(migrations)-1].version {
+		return fmt.Errorf("不存在的结构版本: %d", version)
+	}
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.version <= current || m.version > version {
+			continue
+		}
+		if err = s.applyMigration(m, true); err != nil {
+			return fmt.Errorf("应用迁移%d_%s失败: %v", m.version, m.name, err)
+		}
+		if progress != nil {
+			progress(repo.Migration{Version: m.version, Name: m.name}, true)
+		}
+	}
+	for _, m := range migrations {
+		if m.version <= current && m.version > version && m.down == nil {
+			return fmt.Errorf("迁移%d_%s不可回退", m.version, m.name)
+		}
+	}
+	for index := len(migrations) - 1; index >= 0; index-- {
+		m := migrations[index]
+		if m.version > current || m.version <= version {
+			continue
+		}
+		if err = s.applyMigration(m, false); err != nil {
+			return fmt.Errorf("回退迁移%d_%s失败: %v", m.version, m.name, err)
+		}
+		if progress != nil {
+			progress(repo.Migration{Version: m.version, Name: m.name}, false)
+		}
+	}
+	return nil
+}
+
+// 迁移语句和schema_version的记录在同一个事务中执行; mysql的DDL会隐式提交, 只有sqlite能保证整体回滚
+func (s *store) applyMigration(m migration, up bool) error {
+	statements := m.up[s.dialect]
+	if !up {
+		statements = m.down[s.dialect]
+	}
+	conn, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if _, err = conn.Exec(statement); err != nil {
+			_ = conn.Rollback()
+			return err
+		}
+	}
+	if up {
+		_, err = conn.Exec("insert into schema_version (version, name) values(?, ?)", m.version, m.name)
+	} else {
+		_, err = conn.Exec("delete from schema_version where version = ?", m.version)
+	}
+	if err != nil {
+		_ = conn.Rollback()
+		return err
+	}
+	return conn.Commit()
+}
+
+var contentSchemaDown = []string{
+	"drop table if exists content_schema",
+}
+
+// 已有的item都按纯文本处理, 不做格式校验
+var contentTypeUp = []string{
+	"alter table item add column content_type varchar(16) not null default 'text'",
+}
+
+// 回退前需先取消全部item的加密, 否则回退后密文会被当作明文返回
+var itemEncryptedUp = []string{
+	"alter table item add column encrypted tinyint not null default 0",
+}
+
+// 每个版本单独记录是否加密, 修改item的加密状态时不再改写历史版本; 已有版本按所属item当前的状态补齐.
+// 回退会丢失各版本的加密状态, 只能在尚未修改过item加密状态时回退
+var versionEncryptedUp = []string{
+	"alter table version add column encrypted tinyint not null default 0",
+	"update version set encrypted = 1 where resource_type = 3 and resource_id in (select id from item where encrypted = 1)",
+}
+
+// 早期版本创建和修改组织时把私有存为1, 与project、item以及读取时的约定(1为公开)相反.
+// 这些组织都是在引入版本记录之前写入的, 没有任何版本; 之后写入的组织已按1为公开存储, 不受影响.
+// 回退时不改动数据, 引入结构迁移之后的代码都按1为公开读写
+var orgVisibilityUp = []string{
+	"update privilege set resource_visibility = 1 - resource_visibility where resource_type = 1 and " +
+		"resource_id not in (select resource_id from version where resource_type = 1)",
+	"update org set visibility = 1 - visibility where id not in (select resource_id from version where resource_type = 1)",
+}
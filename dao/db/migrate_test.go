@@ -0,0 +1,213 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"zoe/config"
+)
+
+func newTestSqlite(t *testing.T) *store {
+	dir, err := ioutil.TempDir("", "guldan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	c := &config.Config{}
+	c.Database.ConnectionString = filepath.Join(dir, "test.db")
+	s, err := InitSqlite(c)
+	if err != nil {
+		t.Fatalf("InitSqlite() error = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s.(*store)
+}
+
+// name为"表"或"表.列"
+func hasSchema(t *testing.T, s *store, name string) bool {
+	table, column := name, ""
+	if index := strings.Index(name, "."); index > 0 {
+		table, column = name[:index], name[index+1:]
+	}
+	rows, err := s.db.Query("select name from pragma_table_info(?)", table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	found := false
+	for rows.Next() {
+		var columnName string
+		if err = rows.Scan(&columnName); err != nil {
+			t.Fatal(err)
+		}
+		if column == "" || columnName == column {
+			found = true
+		}
+	}
+	return found
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	s := newTestSqlite(t)
+	latest := migrations[len(migrations)-1].version
+	cases := []struct {
+		version int
+		present []string
+		absent  []string
+	}{
+		{latest, []string{"version.encrypted", "item.encrypted", "content_schema", "item.content_type", "environment"}, nil},
+		{6, []string{"version.encrypted"}, nil},
+		{5, []string{"item.encrypted", "version.content"}, []string{"version.encrypted"}},
+		{4, []string{"content_schema"}, []string{"item.encrypted"}},
+		{3, []string{"item.content_type"}, []string{"content_schema"}},
+		{2, []string{"environment", "item.environment", "privilege.environment"}, []string{"item.content_type"}},
+		{1, []string{"item", "version", "user"}, []string{"environment", "item.environment", "privilege.environment"}},
+		{latest, []string{"version.encrypted", "item.encrypted", "environment"}, nil},
+	}
+	for _, tc := range cases {
+		if err := s.MigrateTo(tc.version, nil); err != nil {
+			t.Fatalf("MigrateTo(%d) error = %v", tc.version, err)
+		}
+		version, err := s.SchemaVersion()
+		if err != nil || version != tc.version {
+			t.Fatalf("SchemaVersion() = %d, %v, want %d", version, err, tc.version)
+		}
+		for _, name := range tc.present {
+			if !hasSchema(t, s, name) {
+				t.Errorf("version %d: %s should exist", tc.version, name)
+			}
+		}
+		for _, name := range tc.absent {
+			if hasSchema(t, s, name) {
+				t.Errorf("version %d: %s should not exist", tc.version, name)
+			}
+		}
+	}
+}
+
+func TestMigrateInitIrreversible(t *testing.T) {
+	s := newTestSqlite(t)
+	latest := migrations[len(migrations)-1].version
+	for _, from := range []int{1, latest} {
+		if err := s.MigrateTo(from, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.MigrateTo(0, nil); err == nil {
+			t.Errorf("MigrateTo(0) from %d should fail", from)
+		}
+		// 回退前整体检查, 不会先回退一部分
+		if version, err := s.SchemaVersion(); err != nil || version != from {
+			t.Errorf("SchemaVersion() = %d, %v, want %d", version, err, from)
+		}
+		if !hasSchema(t, s, "org") {
+			t.Error("org should be kept")
+		}
+	}
+}
+
+func TestMigrateInvalidVersion(t *testing.T) {
+	s := newTestSqlite(t)
+	for _, version := range []int{-1, migrations[len(migrations)-1].version + 1} {
+		if err := s.MigrateTo(version, nil); err == nil {
+			t.Errorf("MigrateTo(%d) should fail", version)
+		}
+	}
+}
+
+func TestMigrateVersionEncrypted(t *testing.T) {
+	s := newTestSqlite(t)
+	if err := s.MigrateTo(5, nil); err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		"insert into item (id, name, parent_id, content, encrypted) values (1, 'acme.web.secret', 1, 'k1:xxx', 1)",
+		"insert into item (id, name, parent_id, content, encrypted) values (2, 'acme.web.plain', 1, 'a=1', 0)",
+		"insert into version (id, resource_id, resource_type, resource_name, content) values (1, 1, 3, 'acme.web.secret', 'k1:xxx')",
+		"insert into version (id, resource_id, resource_type, resource_name, content) values (2, 2, 3, 'acme.web.plain', 'a=1')",
+		// 与加密item同id的org版本不受影响
+		"insert into version (id, resource_id, resource_type, resource_name, content) values (3, 1, 1, 'acme', '')",
+	}
+	for _, statement := range statements {
+		if _, err := s.db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.MigrateTo(6, nil); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		versionId int
+		encrypted int
+	}{
+		{1, 1},
+		{2, 0},
+		{3, 0},
+	}
+	for _, tc := range cases {
+		var encrypted int
+		if err := s.db.QueryRow("select encrypted from version where id = ?", tc.versionId).Scan(&encrypted); err != nil {
+			t.Fatal(err)
+		}
+		if encrypted != tc.encrypted {
+			t.Errorf("version %d encrypted = %d, want %d", tc.versionId, encrypted, tc.encrypted)
+		}
+	}
+	if err := s.MigrateTo(5, nil); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := s.db.QueryRow("select count(*) from version").Scan(&count); err != nil || count != len(cases) {
+		t.Errorf("versions after rollback = %d, %v, want %d", count, err, len(cases))
+	}
+}
+
+func TestMigrateOrgVisibility(t *testing.T) {
+	s := newTestSqlite(t)
+	if err := s.MigrateTo(6, nil); err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		// 旧代码写入的私有组织和公开组织, 没有版本
+		"insert into org (id, name, visibility) values (1, 'legacy_private', 1)",
+		"insert into org (id, name, visibility) values (2, 'legacy_public', 0)",
+		// 新代码写入的公开组织, 带有版本
+		"insert into org (id, name, visibility) values (3, 'current_public', 1)",
+		"insert into version (resource_id, resource_type, resource_name, visibility, content) values (3, 1, 'current_public', 1, '')",
+		"insert into privilege (resource_id, resource_name, resource_type, resource_visibility, user_id, user_hash, privilege_type) " +
+			"values (1, 'legacy_private', 1, 1, 1, 'u1', 2)",
+		"insert into privilege (resource_id, resource_name, resource_type, resource_visibility, user_id, user_hash, privilege_type) " +
+			"values (3, 'current_public', 1, 1, 1, 'u1', 2)",
+	}
+	for _, statement := range statements {
+		if _, err := s.db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.MigrateTo(7, nil); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		orgId      int
+		visibility int
+	}{
+		{1, 0},
+		{2, 1},
+		{3, 1},
+	}
+	for _, tc := range cases {
+		var visibility, resourceVisibility int
+		if err := s.db.QueryRow("select visibility from org where id = ?", tc.orgId).Scan(&visibility); err != nil {
+			t.Fatal(err)
+		}
+		if visibility != tc.visibility {
+			t.Errorf("org %d visibility = %d, want %d", tc.orgId, visibility, tc.visibility)
+		}
+		err := s.db.QueryRow("select resource_visibility from privilege where resource_id = ? and resource_type = 1", tc.orgId).
+			Scan(&resourceVisibility)
+		if err == nil && resourceVisibility != tc.visibility {
+			t.Errorf("org %d privilege visibility = %d, want %d", tc.orgId, resourceVisibility, tc.visibility)
+		}
+	}
+}
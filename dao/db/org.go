@@ -5,27 +5,18 @@ import (
 	"fmt"
 	"github.com/cihub/seelog"
 	"strings"
+	"zoe/basic"
+	"zoe/dao/repo"
 	"zoe/model"
 )
 
-func getOrgById(conn *sql.Tx, id int) (*model.Org, error) {
-	var org model.Org
-	sql := "select * from org where id = ? and is_deleted = 0"
-	err := conn.QueryRow(sql, id).Scan(&org.Id, &org.Name, &org.Visibility, &org.CurrentVersionId, &org.IsDeleted, &org.UpdatedAt, &org.CreateAt)
-	if err != nil {
-		if err.Error() == "sql: no rows in result set" {
-			return nil, nil
-		}
-		seelog.Info(err.Error())
-		return nil, err
-	}
-	return &org, nil
+type orgRepo struct {
+	conn *sql.Tx
 }
 
-func getOrgByName(conn *sql.Tx, name string) (*model.Org, error) {
+func (r orgRepo) queryOne(sql string, args ...interface{}) (*model.Org, error) {
 	var org model.Org
-	sql := "select * from org where name = ? and is_deleted = 0"
-	err := conn.QueryRow(sql, name).Scan(&org.Id, &org.Name, &org.Visibility, &org.CurrentVersionId, &org.IsDeleted, &org.UpdatedAt, &org.CreateAt)
+	err := r.conn.QueryRow(sql, args...).Scan(&org.Id, &org.Name, &org.Visibility, &org.CurrentVersionId, &org.IsDeleted, &org.UpdatedAt, &org.CreateAt)
 	if err != nil {
 		if err.Error() == "sql: no rows in result set" {
 			return nil, nil
@@ -36,99 +27,123 @@ func getOrgByName(conn *sql.Tx, name string) (*model.Org, error) {
 	return &org, nil
 }
 
-func QueryOrgById(conn *sql.Tx, id int) (*model.Org, error) {
-	org, err := getOrgById(conn, id)
+func (r orgRepo) GetById(id int) (*model.Org, error) {
+	return r.queryOne("select * from org where id = ? and is_deleted = 0", id)
+}
+
+func (r orgRepo) GetByName(name string) (*model.Org, error) {
+	return r.queryOne("select * from org where name = ? and is_deleted = 0", name)
+}
+
+func (r orgRepo) query(sql string, args ...interface{}) (*[]model.Org, error) {
+	var orgs []model.Org
+	rows, err := r.conn.Query(sql, args...)
 	if err != nil {
+		_ = seelog.Critical(err)
 		return nil, err
 	}
-	return org, nil
+	var org model.Org
+	for rows.Next() {
+		err = rows.Scan(&org.Id, &org.Name, &org.Visibility, &org.CurrentVersionId, &org.IsDeleted, &org.UpdatedAt, &org.CreateAt)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return &orgs, nil
 }
 
-func IsExistingOrgByName(conn *sql.Tx, name string) (bool, error) {
-	org, err := getOrgByName(conn, name)
-	if err != nil {
-		return false, err
+func (r orgRepo) ListByNames(names []string) (*[]model.Org, error) {
+	var orgs []model.Org
+	cnt := len(names)
+	if cnt == 0 {
+		return &orgs, nil
 	}
-	if org != nil {
-		return true, nil
+	sqlItems := make([]string, cnt)
+	for index := range sqlItems {
+		sqlItems[index] = "?"
 	}
-	return false, nil
+	sqlItemsStr := strings.Join(sqlItems, ",")
+	sql := fmt.Sprintf("select * from org where name in (%s) and is_deleted = 0", sqlItemsStr)
+	params := make([]interface{}, cnt, cnt)
+	for index := range params {
+		params[index] = names[index]
+	}
+	return r.query(sql, params...)
 }
 
-func IsExistingOrgById(conn *sql.Tx, id int) (bool, error) {
-	org, err := getOrgById(conn, id)
+// 项目和item上的授权通过所属项目找到组织
+const orgOfUserWhere = "is_deleted = 0 and id in (" +
+	"select resource_id from privilege where user_hash = ? and resource_type = ? and privilege_type in (?, ?) and is_deleted = 0 " +
+	"union select project.parent_id from privilege join project on project.id = privilege.resource_id " +
+	"where privilege.user_hash = ? and privilege.resource_type = ? and privilege.privilege_type in (?, ?) and privilege.is_deleted = 0 " +
+	"union select project.parent_id from privilege join item on item.id = privilege.resource_id join project on project.id = item.parent_id " +
+	"where privilege.user_hash = ? and privilege.resource_type = ? and privilege.privilege_type in (?, ?) and privilege.is_deleted = 0)"
+
+var orgSortColumns = map[string]string{"id": "id", "name": "name", "created_at": "created_at"}
+
+func (r orgRepo) ListPageByUserHash(userHash string, orgId int, query repo.PageQuery, offset, limit int) (*[]model.Org, int, error) {
+	where := orgOfUserWhere
+	var args []interface{}
+	for _, resType := range []int{basic.Resource_Type_ORG, basic.Resource_Type_PROJECT, basic.Resource_Type_ITEM} {
+		args = append(args, userHash, resType, basic.Privilege_Type_VIEWER, basic.Privilege_Type_MODIFIER)
+	}
+	if orgId != 0 {
+		where += " and id = ?"
+		args = append(args, orgId)
+	}
+	condition, conditionArgs := nameCondition("name", query)
+	where += condition
+	args = append(args, conditionArgs...)
+	var total int
+	err := r.conn.QueryRow("select count(*) from org where "+where, args...).Scan(&total)
 	if err != nil {
-		return false, err
+		return nil, 0, err
 	}
-	if org != nil {
-		return true, nil
+	orgs, err := r.query("select * from org where "+where+orderBy(orgSortColumns, query)+" limit ? offset ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
 	}
-	return false, nil
+	return orgs, total, nil
 }
 
-func CreateOrg(conn *sql.Tx, name string, visibility int) (int, error) {
+func (r orgRepo) Create(name string, visibility int) (int, error) {
 	sql := "insert into org(name, visibility) values(?, ?)"
-	r, err := conn.Exec(sql, name, visibility)
+	res, err := r.conn.Exec(sql, name, visibility)
 	if err != nil {
 		return 0, err
 	}
-	id, err := r.LastInsertId()
+	id, err := res.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
 	return int(id), nil
 }
 
-func UpdateOrg(conn *sql.Tx, orgId int, private bool) error {
-	visibility := 0
-	if private {
-		visibility = 1
-	}
+func (r orgRepo) UpdateVisibility(id, visibility int) error {
 	sql := "update org set visibility = ? where id = ? and is_deleted = 0"
-	_, err := conn.Exec(sql, visibility, orgId)
+	_, err := r.conn.Exec(sql, visibility, id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func DeleteOrg(conn *sql.Tx, orgId int) error {
-	// todo 删除组织的所有project和item
-	sql := "update org set is_deleted = 1 where id = ?"
-	_, err := conn.Exec(sql, orgId)
+func (r orgRepo) UpdateCurrentVersionId(id, versionId int) error {
+	sql := "update org set current_version_id = ? where id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, versionId, id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
-func ListOrg(conn *sql.Tx, orgNames *[]string) (*[]model.Org, error) {
-	var orgs []model.Org
-	cnt := len(*orgNames)
-	if cnt == 0 {
-		return &orgs, nil
-	}
-	sqlItems := make([]string, cnt)
-	for index := range sqlItems {
-		sqlItems[index] = "?"
-	}
-	sqlItemsStr := strings.Join(sqlItems, ",")
-	sql := fmt.Sprintf("select * from org where name in (%s) and is_deleted = 0", sqlItemsStr)
-	params := make([]interface{}, cnt, cnt)
-	for index := range params {
-		params[index] = (*orgNames)[index]
-	}
-	var org model.Org
-	rows, err := conn.Query(sql, params...)
+
+func (r orgRepo) Delete(id int) error {
+	sql := "update org set is_deleted = 1 where id = ?"
+	_, err := r.conn.Exec(sql, id)
 	if err != nil {
-		_ = seelog.Critical(err)
-		return nil, err
-	}
-	for rows.Next() {
-		err = rows.Scan(&org.Id, &org.Name, &org.Visibility, &org.CurrentVersionId, &org.IsDeleted, &org.UpdatedAt, &org.CreateAt)
-		if err != nil {
-			return nil, err
-		}
-		orgs = append(orgs, org)
+		return err
 	}
-	return &orgs, nil
+	return nil
 }
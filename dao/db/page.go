@@ -0,0 +1,31 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"zoe/dao/repo"
+)
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// 按PageQuery的名称条件生成like子句, 通配符按普通字符匹配; 没有名称条件时返回空串
+func nameCondition(column string, query repo.PageQuery) (string, []interface{}) {
+	if query.Name == "" {
+		return "", nil
+	}
+	pattern := likeEscaper.Replace(strings.ToLower(query.NamePrefix)) + "%" + likeEscaper.Replace(strings.ToLower(query.Name)) + "%"
+	return fmt.Sprintf(" and lower(%s) like ? escape '!'", column), []interface{}{pattern}
+}
+
+// columns为排序字段到列名的映射, 其中必须包含id
+func orderBy(columns map[string]string, query repo.PageQuery) string {
+	column, ok := columns[query.Sort]
+	if !ok {
+		column = columns["id"]
+	}
+	direction := "asc"
+	if query.Desc {
+		direction = "desc"
+	}
+	return fmt.Sprintf(" order by %s %s, %s %s", column, direction, columns["id"], direction)
+}
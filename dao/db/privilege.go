@@ -2,21 +2,26 @@ package db
 
 import (
 	"database/sql"
-	"github.com/cihub/seelog"
 	"zoe/basic"
+	"zoe/dao/repo"
 	"zoe/model"
 )
 
-func queryPrivilege(conn *sql.Tx, sql string, args ...interface{}) (*[]model.Privilege, error) {
+type privilegeRepo struct {
+	conn *sql.Tx
+}
+
+func (r privilegeRepo) query(sql string, args ...interface{}) (*[]model.Privilege, error) {
 	var privileges []model.Privilege
-	rows, err := conn.Query(sql, args...)
+	rows, err := r.conn.Query(sql, args...)
 	if err != nil {
 		return nil, err
 	}
 	var privilege model.Privilege
 	for rows.Next() {
 		err = rows.Scan(&privilege.Id, &privilege.ResourceId, &privilege.ResourceName, &privilege.ResourceType, &privilege.ResourceVisibility,
-			&privilege.UserId, &privilege.UserHash, &privilege.PrivilegeType, &privilege.IsDeleted, &privilege.UpdatedAt, &privilege.CreateAt)
+			&privilege.UserId, &privilege.UserHash, &privilege.PrivilegeType, &privilege.IsDeleted, &privilege.UpdatedAt, &privilege.CreateAt,
+			&privilege.Environment)
 		if err != nil {
 			return nil, err
 		}
@@ -25,160 +30,106 @@ func queryPrivilege(conn *sql.Tx, sql string, args ...interface{}) (*[]model.Pri
 	return &privileges, nil
 }
 
-func IsExistingPrivilege(conn *sql.Tx, userHash, resName string, resId, resType, priType int) (bool, error) {
-	var privilege model.Privilege
-	sql := "select id from privilege where is_deleted = 0 and user_hash = ? and resource_name = ? and resource_id = ? and resource_type = ? and privilege_type = ?"
-	err := conn.QueryRow(sql, userHash, resName, resId, resType, priType).Scan(&privilege.Id)
-	if err != nil {
-		if err.Error() == "sql: no rows in result set" {
-			return false, nil
-		}
-		seelog.Info(err.Error())
-		return false, err
-	}
-	return true, nil
-}
-
-func CreatePrivilege(conn *sql.Tx, userHash, resName string, resId, resType, userId, priType, resVisibility int) error {
-	var sql = "insert into privilege(resource_id, resource_name, resource_type, resource_visibility, user_id, user_hash, privilege_type) values (?, ?, ?, ?, ?, ?, ?)"
-	_, err := conn.Exec(sql, resId, resName, resType, resVisibility, userId, userHash, priType)
+func (r privilegeRepo) Get(userHash, environment string, resId, resType int) (*model.Privilege, error) {
+	sql := "select * from privilege where user_hash = ? and resource_id = ? and resource_type = ? and environment = ? and is_deleted = 0"
+	privileges, err := r.query(sql, userHash, resId, resType, environment)
 	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func AddWithCheck(conn *sql.Tx, userHash, resName string, resId, resType, userId, priType, resVisibility int) error {
-	flag, err := IsExistingPrivilege(conn, userHash, resName, resId, resType, priType)
-	if err != nil {
-		return err
+		return nil, err
 	}
-	if flag {
-		return nil
+	if len(*privileges) > 0 {
+		return &(*privileges)[0], nil
 	}
-	err = CreatePrivilege(conn, userHash, resName, resId, resType, userId, priType, resVisibility)
-	return err
+	return nil, nil
 }
 
-func ValidateForUserModifyOrg(conn *sql.Tx, userHash string, orgId int) (bool, error) {
-	var privilege model.Privilege
-	sql := "select id, privilege_type from privilege where user_hash = ? and resource_id = ? and resource_type = ? and is_deleted = 0"
-	err := conn.QueryRow(sql, userHash, orgId, basic.Resource_Type_ORG).Scan(&privilege.Id, &privilege.PrivilegeType)
-	if err != nil {
-		return false, nil
-	}
-	if privilege.PrivilegeType == basic.Privilege_Type_MODIFIER {
-		return true, nil
-	} else {
-		return false, nil
-	}
+func (r privilegeRepo) ListByUserHash(userHash string) (*[]model.Privilege, error) {
+	sql := "select * from privilege where user_hash  = ? and resource_type in (?, ?, ?) and privilege_type in (?, ?) and is_deleted = 0"
+	return r.query(sql, userHash, basic.Resource_Type_ORG, basic.Resource_Type_PROJECT, basic.Resource_Type_ITEM,
+		basic.Privilege_Type_MODIFIER, basic.Privilege_Type_VIEWER)
 }
 
-func ValidateForUserViewOrg(conn *sql.Tx, userHash string, orgId int) (bool, error) {
-	var privilege model.Privilege
-	sql := "select id, privilege_type from privilege where user_hash = ? and resource_id = ? and resource_type = ? and is_deleted = 0"
-	err := conn.QueryRow(sql, userHash, orgId, basic.Resource_Type_ORG).Scan(&privilege.Id, &privilege.PrivilegeType)
-	if err != nil {
-		return false, err
-	}
-	if privilege.PrivilegeType >= basic.Privilege_Type_VIEWER {
-		return true, nil
-	} else {
-		return false, nil
-	}
+func (r privilegeRepo) ListByResource(resId, resType int) (*[]model.Privilege, error) {
+	return r.query("select * from privilege where resource_id = ? and resource_type = ? and is_deleted = 0", resId, resType)
 }
 
-func DeletePrivilege(conn *sql.Tx, resId, resType int) error {
-	sql := "update privilege set is_deleted = 1 where resource_id = ? and resource_type = ? and is_deleted = 0"
-	_, err := conn.Exec(sql, resId, resType)
-	if err != nil {
-		return err
-	}
-	return nil
+var privilegeSortColumns = map[string]string{
+	"id":         "privilege.id",
+	"user_name":  "user.name",
+	"type":       "privilege.privilege_type",
+	"created_at": "privilege.created_at",
 }
 
-func ListPrivilege(conn *sql.Tx, userHash string) (*[]model.Privilege, error) {
-	sql := "select * from privilege where user_hash  = ? and resource_type in (?, ?, ?) and privilege_type in (?, ?) and is_deleted = 0"
-	privileges, err := queryPrivilege(conn, sql, userHash, basic.Resource_Type_ORG, basic.Resource_Type_PROJECT, basic.Resource_Type_ITEM,
-		basic.Privilege_Type_MODIFIER, basic.Privilege_Type_VIEWER)
+func (r privilegeRepo) ListPageByResource(resId, resType int, query repo.PageQuery, offset, limit int) (*[]model.Privilege, int, error) {
+	from := "from privilege left join user on user.id = privilege.user_id " +
+		"where privilege.resource_id = ? and privilege.resource_type = ? and privilege.is_deleted = 0"
+	args := []interface{}{resId, resType}
+	condition, conditionArgs := nameCondition("user.name", query)
+	from += condition
+	args = append(args, conditionArgs...)
+	var total int
+	err := r.conn.QueryRow("select count(*) "+from, args...).Scan(&total)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return privileges, nil
-}
-
-func ListPrivilegeByResource(conn *sql.Tx, resId, resType int) (*[]model.Privilege, error) {
-	sql := "select * from privilege where resource_id = ? and resource_type = ? and is_deleted = 0"
-	privileges, err := queryPrivilege(conn, sql, resId, resType)
+	privileges, err := r.query("select privilege.* "+from+orderBy(privilegeSortColumns, query)+" limit ? offset ?",
+		append(args, limit, offset)...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return privileges, nil
+	return privileges, total, nil
 }
 
-func ListPrivilegeByPrefixResourceName(conn *sql.Tx, name, userHash string) (*[]model.Privilege, error) {
-	sql := "select * from privilege where user_hash = ? and resource_name like '?%' and is_deleted = 0"
-	privileges, err := queryPrivilege(conn, sql, userHash, name)
-	if err != nil {
-		return nil, err
-	}
-	return privileges, nil
+func (r privilegeRepo) ListByResources(userHash string, resIds map[int]int) (*[]model.Privilege, error) {
+	sql := "select * from privilege where user_hash = ? and is_deleted = 0 and " +
+		"((resource_type = ? and resource_id = ?) or (resource_type = ? and resource_id = ?) or (resource_type = ? and resource_id = ?))"
+	return r.query(sql, userHash,
+		basic.Resource_Type_ORG, resIds[basic.Resource_Type_ORG],
+		basic.Resource_Type_PROJECT, resIds[basic.Resource_Type_PROJECT],
+		basic.Resource_Type_ITEM, resIds[basic.Resource_Type_ITEM])
 }
 
-func QueryPrivilegeByUserHash(conn *sql.Tx, userHash string, resId, resType int) (*model.Privilege, error) {
-	sql := "select * from privilege where user_hash = ? and resource_id = ? and resource_type = ? and is_deleted = 0"
-	privileges, err := queryPrivilege(conn, sql, userHash, resId, resType)
+func (r privilegeRepo) Create(userHash, resName, environment string, resId, resType, userId, priType, resVisibility int) error {
+	sql := "insert into privilege(resource_id, resource_name, resource_type, resource_visibility, user_id, user_hash, privilege_type, environment) " +
+		"values (?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err := r.conn.Exec(sql, resId, resName, resType, resVisibility, userId, userHash, priType, environment)
 	if err != nil {
-		return nil, err
-	}
-	if len(*privileges) > 0 {
-		return &(*privileges)[0], nil
+		return err
 	}
-	return nil, nil
+	return nil
 }
 
-func UpdatePrivilegeByUserHash(conn *sql.Tx, userHash string, priType, resId, resType int) error {
-	sql := "update privilege set privilege_type = ? where user_hash = ? and resource_id = ? and resource_type = ?"
-	_, err := conn.Exec(sql, priType, userHash, resId, resType)
+func (r privilegeRepo) Update(userHash, environment string, priType, resId, resType int) error {
+	sql := "update privilege set privilege_type = ? where user_hash = ? and resource_id = ? and resource_type = ? and environment = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, priType, userHash, resId, resType, environment)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func DeletePrivilegeByUserHash(conn *sql.Tx, userHash string, resId, resType int) error {
-	sql := "update privilege set is_deleted = 1 where user_hash = ? and resource_id = ? and resource_type = ? and is_deleted = 0"
-	_, err := conn.Exec(sql, userHash, resId, resType)
+func (r privilegeRepo) Delete(userHash, environment string, resId, resType int) error {
+	sql := "update privilege set is_deleted = 1 where user_hash = ? and resource_id = ? and resource_type = ? and environment = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, userHash, resId, resType, environment)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func ValidateUserForProjectCreation(conn *sql.Tx, userHash string, orgId int) (bool, error) {
-	flag, err := ValidateForUserViewOrg(conn, userHash, orgId)
+func (r privilegeRepo) DeleteByResource(resId, resType int) error {
+	sql := "update privilege set is_deleted = 1 where resource_id = ? and resource_type = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, resId, resType)
 	if err != nil {
-		return false, err
+		return err
 	}
-	return flag, nil
+	return nil
 }
 
-func ValidateForUserModifyProject(conn *sql.Tx, userHash string, projectId, orgId int) (bool, error) {
-	sql := "select * from privilege where user_hash = ? and resource_id = ? and resource_type = ? and is_deleted = 0"
-	privileges, err := queryPrivilege(conn, sql, userHash, projectId, basic.Resource_Type_PROJECT)
+func (r privilegeRepo) DeleteByResourceAndEnvironment(resId, resType int, environment string) error {
+	sql := "update privilege set is_deleted = 1 where resource_id = ? and resource_type = ? and environment = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, resId, resType, environment)
 	if err != nil {
-		return false, err
-	}
-	if len(*privileges) > 0 {
-		return true, nil
-	}
-	flag, err := ValidateForUserModifyOrg(conn, userHash, orgId)
-	if err != nil {
-		return false, err
-	}
-	if flag {
-		return true, nil
-	} else {
-		return false, nil
+		return err
 	}
+	return nil
 }
@@ -2,14 +2,18 @@ package db
 
 import (
 	"database/sql"
-	"errors"
-	"github.com/cihub/seelog"
+	"strings"
+	"zoe/dao/repo"
 	"zoe/model"
 )
 
-func queryProject(conn *sql.Tx, sql string, args ...interface{}) (*[]model.Project, error) {
+type projectRepo struct {
+	conn *sql.Tx
+}
+
+func (r projectRepo) query(sql string, args ...interface{}) (*[]model.Project, error) {
 	var projects []model.Project
-	rows, err := conn.Query(sql, args...)
+	rows, err := r.conn.Query(sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -25,9 +29,8 @@ func queryProject(conn *sql.Tx, sql string, args ...interface{}) (*[]model.Proje
 	return &projects, nil
 }
 
-func GetProjectById(conn *sql.Tx, id int) (*model.Project, error) {
-	sql := "select * from project where id = ? and is_deleted = 0"
-	projects, err := queryProject(conn, sql, id)
+func (r projectRepo) queryOne(sql string, args ...interface{}) (*model.Project, error) {
+	projects, err := r.query(sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -37,86 +40,85 @@ func GetProjectById(conn *sql.Tx, id int) (*model.Project, error) {
 	return nil, nil
 }
 
-func GetProjectByParentIdAndName(conn *sql.Tx, parentId int, name string) (*model.Project, error) {
-	sql := "select * from project where name = ? and parent_id = ? and is_deleted = 0"
-	projects, err := queryProject(conn, sql, name, parentId)
-	if err != nil {
-		return nil, err
-	}
-	if len(*projects) > 0 {
-		return &(*projects)[0], nil
-	}
-	return nil, nil
+func (r projectRepo) GetById(id int) (*model.Project, error) {
+	return r.queryOne("select * from project where id = ? and is_deleted = 0", id)
 }
 
-func ListProject(conn *sql.Tx, orgId int) (*[]model.Project, error) {
-	sql := "select * from project where parent_id = ? and is_deleted = 0"
-	projects, err := queryProject(conn, sql, orgId)
-	if err != nil {
-		return nil, err
-	}
-	return projects, nil
+func (r projectRepo) GetByParentIdAndName(parentId int, name string) (*model.Project, error) {
+	return r.queryOne("select * from project where name = ? and parent_id = ? and is_deleted = 0", name, parentId)
 }
 
-func ListProjectByVisibility(conn *sql.Tx, orgId int) (*[]model.Project, *[]model.Project, error) {
-	projects, err := ListProject(conn, orgId)
-	if err != nil {
-		return nil, nil, err
-	}
-	var publicProjects []model.Project
-	var privateProjects []model.Project
-	for _, item := range *projects {
-		if item.Visibility == 1 {
-			publicProjects = append(publicProjects, item)
-		} else if item.Visibility == 0 {
-			privateProjects = append(privateProjects, item)
-		} else {
-			_ = seelog.Critical("unKown project")
-		}
-	}
-	return &publicProjects, &privateProjects, nil
+func (r projectRepo) ListByParentId(orgId int) (*[]model.Project, error) {
+	return r.query("select * from project where parent_id = ? and is_deleted = 0", orgId)
 }
 
-func CreateProject(conn *sql.Tx, name string, visibility int, parentId int) (int, error) {
-	project, err := GetProjectByParentIdAndName(conn, parentId, name)
-	if err != nil {
-		return 0, err
-	}
-	if project != nil {
-		return 0, errors.New("该project已经存在")
-	}
+func (r projectRepo) Create(name string, visibility, parentId int) (int, error) {
 	sql := "insert into project (name, parent_id, visibility) values(?, ?, ?)"
-	r, err := conn.Exec(sql, name, parentId, visibility)
+	res, err := r.conn.Exec(sql, name, parentId, visibility)
 	if err != nil {
 		return 0, err
 	}
-	id, err := r.LastInsertId()
+	id, err := res.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
 	return int(id), nil
 }
 
-func UpdateProject(conn *sql.Tx, projectId int, private string) error {
-	var visibility int
-	if private == "true" {
-		visibility = 0
-	} else if private == "false" {
-		visibility = 1
-	}
+func (r projectRepo) UpdateVisibility(id, visibility int) error {
 	sql := "update project set visibility = ? where id = ? and is_deleted = 0"
-	_, err := conn.Exec(sql, visibility, projectId)
+	_, err := r.conn.Exec(sql, visibility, id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func ListProjectByParentId(conn *sql.Tx, orgId int) (*[]model.Project, error) {
-	sql := "select * from project where parent_id = ? and is_deleted = 0"
-	projects, err := queryProject(conn, sql, orgId)
+func (r projectRepo) UpdateCurrentVersionId(id, versionId int) error {
+	sql := "update project set current_version_id = ? where id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, versionId, id)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	return nil
+}
+
+func (r projectRepo) Delete(id int) error {
+	sql := "update project set is_deleted = 1 where id = ?"
+	_, err := r.conn.Exec(sql, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+var projectSortColumns = map[string]string{"id": "id", "name": "name", "created_at": "created_at"}
+
+func (r projectRepo) ListPageByParentId(orgId int, visibleIds []int, query repo.PageQuery, offset, limit int) (*[]model.Project, int, error) {
+	where := "parent_id = ? and is_deleted = 0"
+	args := []interface{}{orgId}
+	if visibleIds != nil {
+		if len(visibleIds) == 0 {
+			where += " and visibility = 1"
+		} else {
+			where += " and (visibility = 1 or id in (?" + strings.Repeat(", ?", len(visibleIds)-1) + "))"
+			for _, id := range visibleIds {
+				args = append(args, id)
+			}
+		}
+	}
+	condition, conditionArgs := nameCondition("name", query)
+	where += condition
+	args = append(args, conditionArgs...)
+	var total int
+	err := r.conn.QueryRow("select count(*) from project where "+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	projects, err := r.query("select * from project where "+where+orderBy(projectSortColumns, query)+" limit ? offset ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
 	}
-	return projects, nil
+	return projects, total, nil
 }
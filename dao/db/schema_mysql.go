@@ -0,0 +1,198 @@
+package db
+
+// 已有部署的表可能是手工创建的, 这里都用if not exists, 首次迁移只补齐缺少的表
+var mysqlInitUp = []string{
+	`create table if not exists org (
+	id int not null auto_increment,
+	name varchar(255) not null,
+	visibility tinyint not null default 0,
+	current_version_id int not null default 0,
+	is_deleted tinyint not null default 0,
+	updated_at datetime not null default current_timestamp on update current_timestamp,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_name (name)
+) engine = InnoDB default charset = utf8mb4`,
+	`create table if not exists project (
+	id int not null auto_increment,
+	name varchar(255) not null,
+	parent_id int not null,
+	visibility tinyint not null default 0,
+	current_version_id int not null default 0,
+	is_deleted tinyint not null default 0,
+	updated_at datetime not null default current_timestamp on update current_timestamp,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_parent_id (parent_id)
+) engine = InnoDB default charset = utf8mb4`,
+	`create table if not exists item (
+	id int not null auto_increment,
+	name varchar(255) not null,
+	parent_id int not null,
+	visibility tinyint not null default 0,
+	content mediumtext not null,
+	current_version_id int not null default 0,
+	is_deleted tinyint not null default 0,
+	updated_at datetime not null default current_timestamp on update current_timestamp,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_parent_id (parent_id)
+) engine = InnoDB default charset = utf8mb4`,
+	`create table if not exists version (
+	id int not null auto_increment,
+	resource_id int not null,
+	resource_type tinyint not null,
+	resource_name varchar(255) not null,
+	visibility tinyint not null default 0,
+	content mediumtext not null,
+	user_id int not null default 0,
+	rollback_from int not null default 0,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_resource (resource_id, resource_type)
+) engine = InnoDB default charset = utf8mb4`,
+	`create table if not exists draft (
+	id int not null auto_increment,
+	item_id int not null,
+	content mediumtext not null,
+	user_id int not null,
+	is_deleted tinyint not null default 0,
+	updated_at datetime not null default current_timestamp on update current_timestamp,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_item_id (item_id)
+) engine = InnoDB default charset = utf8mb4`,
+	`create table if not exists gray_release (
+	id int not null auto_increment,
+	item_id int not null,
+	version_id int not null,
+	client_ids text not null,
+	ip_ranges text not null,
+	percentage int not null default 0,
+	status tinyint not null default 0,
+	user_id int not null,
+	is_deleted tinyint not null default 0,
+	updated_at datetime not null default current_timestamp on update current_timestamp,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_item_id (item_id)
+) engine = InnoDB default charset = utf8mb4`,
+	`create table if not exists privilege (
+	id int not null auto_increment,
+	resource_id int not null,
+	resource_name varchar(255) not null,
+	resource_type tinyint not null,
+	resource_visibility tinyint not null default 0,
+	user_id int not null,
+	user_hash varchar(64) not null,
+	privilege_type tinyint not null,
+	is_deleted tinyint not null default 0,
+	updated_at datetime not null default current_timestamp on update current_timestamp,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_user_hash (user_hash),
+	key idx_resource (resource_id, resource_type)
+) engine = InnoDB default charset = utf8mb4`,
+	`create table if not exists user (
+	id int not null auto_increment,
+	name varchar(64) not null,
+	user_hash varchar(64) not null,
+	secret_hash varchar(255) not null,
+	is_deleted tinyint not null default 0,
+	updated_at datetime not null default current_timestamp on update current_timestamp,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_name (name),
+	key idx_user_hash (user_hash)
+) engine = InnoDB default charset = utf8mb4`,
+	`create table if not exists session (
+	id int not null auto_increment,
+	user_id int not null,
+	token_hash varchar(64) not null,
+	expired_at datetime not null,
+	is_deleted tinyint not null default 0,
+	updated_at datetime not null default current_timestamp on update current_timestamp,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_token_hash (token_hash)
+) engine = InnoDB default charset = utf8mb4`,
+	`create table if not exists token (
+	id int not null auto_increment,
+	user_id int not null,
+	name varchar(255) not null,
+	token_hash varchar(64) not null,
+	read_only tinyint not null default 0,
+	resource_type tinyint not null default 0,
+	resource_id int not null default 0,
+	expired_at datetime not null,
+	last_used_at datetime null,
+	is_deleted tinyint not null default 0,
+	updated_at datetime not null default current_timestamp on update current_timestamp,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_token_hash (token_hash),
+	key idx_user_id (user_id)
+) engine = InnoDB default charset = utf8mb4`,
+	`create table if not exists audit (
+	id int not null auto_increment,
+	org_id int not null,
+	user_id int not null,
+	action varchar(32) not null,
+	resource_type tinyint not null,
+	resource_id int not null,
+	resource_name varchar(255) not null,
+	target_user_id int not null default 0,
+	before_value mediumtext not null,
+	after_value mediumtext not null,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_org_id (org_id)
+) engine = InnoDB default charset = utf8mb4`,
+}
+
+var mysqlEnvironmentUp = []string{
+	`create table if not exists environment (
+	id int not null auto_increment,
+	project_id int not null,
+	name varchar(32) not null,
+	is_deleted tinyint not null default 0,
+	updated_at datetime not null default current_timestamp on update current_timestamp,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	key idx_project_id (project_id)
+) engine = InnoDB default charset = utf8mb4`,
+	"alter table item add column environment varchar(32) not null default ''",
+	"alter table privilege add column environment varchar(32) not null default ''",
+}
+
+var mysqlEnvironmentDown = []string{
+	"alter table privilege drop column environment",
+	"alter table item drop column environment",
+	"drop table if exists environment",
+}
+
+var mysqlContentTypeDown = []string{
+	"alter table item drop column content_type",
+}
+
+var mysqlItemEncryptedDown = []string{
+	"alter table item drop column encrypted",
+}
+
+var mysqlVersionEncryptedDown = []string{
+	"alter table version drop column encrypted",
+}
+
+var mysqlContentSchemaUp = []string{
+	`create table if not exists content_schema (
+	id int not null auto_increment,
+	resource_id int not null,
+	resource_type tinyint not null,
+	version int not null,
+	content mediumtext not null,
+	user_id int not null,
+	created_at datetime not null default current_timestamp,
+	primary key (id),
+	unique key uk_resource_version (resource_id, resource_type, version)
+) engine = InnoDB default charset = utf8mb4`,
+}
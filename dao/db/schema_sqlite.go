@@ -0,0 +1,239 @@
+package db
+
+import "fmt"
+
+// 迁移1中的item和privilege表, 回退迁移2时也用于重建
+const sqliteItemTable = `create table if not exists item (
+	id integer primary key autoincrement,
+	name varchar(255) not null,
+	parent_id integer not null,
+	visibility integer not null default 0,
+	content text not null,
+	current_version_id integer not null default 0,
+	is_deleted integer not null default 0,
+	updated_at datetime not null default current_timestamp,
+	created_at datetime not null default current_timestamp
+)`
+
+const sqlitePrivilegeTable = `create table if not exists privilege (
+	id integer primary key autoincrement,
+	resource_id integer not null,
+	resource_name varchar(255) not null,
+	resource_type integer not null,
+	resource_visibility integer not null default 0,
+	user_id integer not null,
+	user_hash varchar(64) not null,
+	privilege_type integer not null,
+	is_deleted integer not null default 0,
+	updated_at datetime not null default current_timestamp,
+	created_at datetime not null default current_timestamp
+)`
+
+const sqliteVersionTable = `create table if not exists version (
+	id integer primary key autoincrement,
+	resource_id integer not null,
+	resource_type integer not null,
+	resource_name varchar(255) not null,
+	visibility integer not null default 0,
+	content text not null,
+	user_id integer not null default 0,
+	rollback_from integer not null default 0,
+	created_at datetime not null default current_timestamp
+)`
+
+var sqliteInitUp = append([]string{
+	`create table if not exists org (
+	id integer primary key autoincrement,
+	name varchar(255) not null,
+	visibility integer not null default 0,
+	current_version_id integer not null default 0,
+	is_deleted integer not null default 0,
+	updated_at datetime not null default current_timestamp,
+	created_at datetime not null default current_timestamp
+)`,
+	"create index if not exists idx_org_name on org (name)",
+	`create table if not exists project (
+	id integer primary key autoincrement,
+	name varchar(255) not null,
+	parent_id integer not null,
+	visibility integer not null default 0,
+	current_version_id integer not null default 0,
+	is_deleted integer not null default 0,
+	updated_at datetime not null default current_timestamp,
+	created_at datetime not null default current_timestamp
+)`,
+	"create index if not exists idx_project_parent_id on project (parent_id)",
+	sqliteItemTable,
+	"create index if not exists idx_item_parent_id on item (parent_id)",
+	sqliteVersionTable,
+	"create index if not exists idx_version_resource on version (resource_id, resource_type)",
+	`create table if not exists draft (
+	id integer primary key autoincrement,
+	item_id integer not null,
+	content text not null,
+	user_id integer not null,
+	is_deleted integer not null default 0,
+	updated_at datetime not null default current_timestamp,
+	created_at datetime not null default current_timestamp
+)`,
+	"create index if not exists idx_draft_item_id on draft (item_id)",
+	`create table if not exists gray_release (
+	id integer primary key autoincrement,
+	item_id integer not null,
+	version_id integer not null,
+	client_ids text not null,
+	ip_ranges text not null,
+	percentage integer not null default 0,
+	status integer not null default 0,
+	user_id integer not null,
+	is_deleted integer not null default 0,
+	updated_at datetime not null default current_timestamp,
+	created_at datetime not null default current_timestamp
+)`,
+	"create index if not exists idx_gray_release_item_id on gray_release (item_id)",
+	sqlitePrivilegeTable,
+	"create index if not exists idx_privilege_user_hash on privilege (user_hash)",
+	"create index if not exists idx_privilege_resource on privilege (resource_id, resource_type)",
+	`create table if not exists user (
+	id integer primary key autoincrement,
+	name varchar(64) not null,
+	user_hash varchar(64) not null,
+	secret_hash varchar(255) not null,
+	is_deleted integer not null default 0,
+	updated_at datetime not null default current_timestamp,
+	created_at datetime not null default current_timestamp
+)`,
+	"create index if not exists idx_user_name on user (name)",
+	"create index if not exists idx_user_user_hash on user (user_hash)",
+	`create table if not exists session (
+	id integer primary key autoincrement,
+	user_id integer not null,
+	token_hash varchar(64) not null,
+	expired_at datetime not null,
+	is_deleted integer not null default 0,
+	updated_at datetime not null default current_timestamp,
+	created_at datetime not null default current_timestamp
+)`,
+	"create index if not exists idx_session_token_hash on session (token_hash)",
+	`create table if not exists token (
+	id integer primary key autoincrement,
+	user_id integer not null,
+	name varchar(255) not null,
+	token_hash varchar(64) not null,
+	read_only integer not null default 0,
+	resource_type integer not null default 0,
+	resource_id integer not null default 0,
+	expired_at datetime not null,
+	last_used_at datetime,
+	is_deleted integer not null default 0,
+	updated_at datetime not null default current_timestamp,
+	created_at datetime not null default current_timestamp
+)`,
+	"create index if not exists idx_token_token_hash on token (token_hash)",
+	"create index if not exists idx_token_user_id on token (user_id)",
+	`create table if not exists audit (
+	id integer primary key autoincrement,
+	org_id integer not null,
+	user_id integer not null,
+	action varchar(32) not null,
+	resource_type integer not null,
+	resource_id integer not null,
+	resource_name varchar(255) not null,
+	target_user_id integer not null default 0,
+	before_value text not null,
+	after_value text not null,
+	created_at datetime not null default current_timestamp
+)`,
+	"create index if not exists idx_audit_org_id on audit (org_id)",
+}, sqliteUpdatedAtTriggers("org", "project", "item", "draft", "gray_release", "privilege", "user", "session", "token")...)
+
+// sqlite没有on update current_timestamp, 用触发器维护updated_at
+func sqliteUpdatedAtTriggers(tables ...string) []string {
+	triggers := make([]string, len(tables))
+	for index, table := range tables {
+		triggers[index] = fmt.Sprintf(`create trigger if not exists %[1]s_updated_at after update on %[1]s for each row
+begin
+	update %[1]s set updated_at = current_timestamp where id = old.id;
+end`, table)
+	}
+	return triggers
+}
+
+var sqliteEnvironmentUp = []string{
+	`create table if not exists environment (
+	id integer primary key autoincrement,
+	project_id integer not null,
+	name varchar(32) not null,
+	is_deleted integer not null default 0,
+	updated_at datetime not null default current_timestamp,
+	created_at datetime not null default current_timestamp
+)`,
+	"create index if not exists idx_environment_project_id on environment (project_id)",
+	sqliteUpdatedAtTriggers("environment")[0],
+	"alter table item add column environment varchar(32) not null default ''",
+	"alter table privilege add column environment varchar(32) not null default ''",
+}
+
+// 内置的sqlite不支持drop column, 回退时重建item和privilege表
+var sqliteEnvironmentDown = []string{
+	"drop table if exists environment",
+	"alter table item rename to item_environment",
+	sqliteItemTable,
+	"insert into item select id, name, parent_id, visibility, content, current_version_id, is_deleted, updated_at, created_at from item_environment",
+	"drop table item_environment",
+	"create index if not exists idx_item_parent_id on item (parent_id)",
+	sqliteUpdatedAtTriggers("item")[0],
+	"alter table privilege rename to privilege_environment",
+	sqlitePrivilegeTable,
+	"insert into privilege select id, resource_id, resource_name, resource_type, resource_visibility, user_id, user_hash, " +
+		"privilege_type, is_deleted, updated_at, created_at from privilege_environment",
+	"drop table privilege_environment",
+	"create index if not exists idx_privilege_user_hash on privilege (user_hash)",
+	"create index if not exists idx_privilege_resource on privilege (resource_id, resource_type)",
+	sqliteUpdatedAtTriggers("privilege")[0],
+}
+
+var sqliteContentTypeDown = []string{
+	"alter table item rename to item_content_type",
+	sqliteItemTable,
+	"alter table item add column environment varchar(32) not null default ''",
+	"insert into item select id, name, parent_id, visibility, content, current_version_id, is_deleted, updated_at, created_at, " +
+		"environment from item_content_type",
+	"drop table item_content_type",
+	"create index if not exists idx_item_parent_id on item (parent_id)",
+	sqliteUpdatedAtTriggers("item")[0],
+}
+
+var sqliteContentSchemaUp = []string{
+	`create table if not exists content_schema (
+	id integer primary key autoincrement,
+	resource_id integer not null,
+	resource_type integer not null,
+	version integer not null,
+	content text not null,
+	user_id integer not null,
+	created_at datetime not null default current_timestamp
+)`,
+	"create unique index if not exists uk_content_schema_resource_version on content_schema (resource_id, resource_type, version)",
+}
+
+var sqliteItemEncryptedDown = []string{
+	"alter table item rename to item_encrypted",
+	sqliteItemTable,
+	"alter table item add column environment varchar(32) not null default ''",
+	"alter table item add column content_type varchar(16) not null default 'text'",
+	"insert into item select id, name, parent_id, visibility, content, current_version_id, is_deleted, updated_at, created_at, " +
+		"environment, content_type from item_encrypted",
+	"drop table item_encrypted",
+	"create index if not exists idx_item_parent_id on item (parent_id)",
+	sqliteUpdatedAtTriggers("item")[0],
+}
+
+var sqliteVersionEncryptedDown = []string{
+	"alter table version rename to version_encrypted",
+	sqliteVersionTable,
+	"insert into version select id, resource_id, resource_type, resource_name, visibility, content, user_id, rollback_from, " +
+		"created_at from version_encrypted",
+	"drop table version_encrypted",
+	"create index if not exists idx_version_resource on version (resource_id, resource_type)",
+}
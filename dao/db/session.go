@@ -0,0 +1,47 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+	"zoe/model"
+)
+
+type sessionRepo struct {
+	conn *sql.Tx
+}
+
+func (r sessionRepo) GetByTokenHash(tokenHash string) (*model.Session, error) {
+	var session model.Session
+	sql := "select * from session where token_hash = ? and expired_at > ? and is_deleted = 0"
+	err := r.conn.QueryRow(sql, tokenHash, utc(time.Now())).Scan(&session.Id, &session.UserId, &session.TokenHash, &session.ExpiredAt,
+		&session.IsDeleted, &session.UpdatedAt, &session.CreateAt)
+	if err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r sessionRepo) Create(userId int, tokenHash string, expiredAt time.Time) (int, error) {
+	sql := "insert into session (user_id, token_hash, expired_at) values(?, ?, ?)"
+	res, err := r.conn.Exec(sql, userId, tokenHash, utc(expiredAt))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r sessionRepo) Delete(tokenHash string) error {
+	sql := "update session set is_deleted = 1 where token_hash = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, tokenHash)
+	if err != nil {
+		return err
+	}
+	return nil
+}
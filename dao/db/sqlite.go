@@ -0,0 +1,34 @@
+package db
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"strings"
+	"zoe/config"
+	"zoe/dao/repo"
+)
+
+func init() {
+	repo.Register("sqlite", InitSqlite)
+}
+
+// connectionstring为数据库文件路径, 也可以带上go-sqlite3支持的参数, 如 ./guldan.db?_busy_timeout=5000
+func InitSqlite(c *config.Config) (repo.Store, error) {
+	dsn := c.Database.ConnectionString
+	if !strings.Contains(dsn, "_loc=") {
+		if strings.Contains(dsn, "?") {
+			dsn += "&_loc=auto"
+		} else {
+			dsn += "?_loc=auto"
+		}
+	}
+	database, err := sqlx.Open("sqlite3", dsn)
+	if err != nil {
+		fmt.Println("init sqlite error: ", err)
+		return nil, err
+	}
+	// sqlite同一时刻只允许一个写事务, 所有事务共用一个连接串行执行
+	database.SetMaxOpenConns(1)
+	return &store{db: database, dialect: dialectSqlite}, nil
+}
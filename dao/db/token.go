@@ -0,0 +1,83 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+	"zoe/model"
+)
+
+type tokenRepo struct {
+	conn *sql.Tx
+}
+
+func (r tokenRepo) query(sql string, args ...interface{}) (*[]model.Token, error) {
+	var tokens []model.Token
+	rows, err := r.conn.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	var token model.Token
+	for rows.Next() {
+		err = rows.Scan(&token.Id, &token.UserId, &token.Name, &token.TokenHash, &token.ReadOnly, &token.ResourceType,
+			&token.ResourceId, &token.ExpiredAt, &token.LastUsedAt, &token.IsDeleted, &token.UpdatedAt, &token.CreateAt)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return &tokens, nil
+}
+
+func (r tokenRepo) queryOne(sql string, args ...interface{}) (*model.Token, error) {
+	tokens, err := r.query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(*tokens) > 0 {
+		return &(*tokens)[0], nil
+	}
+	return nil, nil
+}
+
+func (r tokenRepo) GetByTokenHash(tokenHash string) (*model.Token, error) {
+	return r.queryOne("select * from token where token_hash = ? and expired_at > ? and is_deleted = 0", tokenHash, utc(time.Now()))
+}
+
+func (r tokenRepo) GetById(id int) (*model.Token, error) {
+	return r.queryOne("select * from token where id = ? and is_deleted = 0", id)
+}
+
+func (r tokenRepo) ListByUserId(userId int) (*[]model.Token, error) {
+	return r.query("select * from token where user_id = ? and is_deleted = 0 order by id desc", userId)
+}
+
+func (r tokenRepo) Create(userId int, name, tokenHash string, readOnly, resType, resId int, expiredAt time.Time) (int, error) {
+	sql := "insert into token (user_id, name, token_hash, read_only, resource_type, resource_id, expired_at) values(?, ?, ?, ?, ?, ?, ?)"
+	res, err := r.conn.Exec(sql, userId, name, tokenHash, readOnly, resType, resId, utc(expiredAt))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r tokenRepo) UpdateLastUsedAt(id int) error {
+	sql := "update token set last_used_at = ? where id = ?"
+	_, err := r.conn.Exec(sql, utc(time.Now()), id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r tokenRepo) Delete(id int) error {
+	sql := "update token set is_deleted = 1 where id = ? and is_deleted = 0"
+	_, err := r.conn.Exec(sql, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -7,27 +7,35 @@ import (
 	"zoe/model"
 )
 
-func GetUserByUserHash(conn *sql.Tx, userHash string) (*model.User, error) {
+type userRepo struct {
+	conn *sql.Tx
+}
+
+func (r userRepo) queryOne(sql string, args ...interface{}) (*model.User, error) {
 	var user model.User
-	sql := "select * from user where user_hash = ? and is_deleted = 0"
-	err := conn.QueryRow(sql, userHash).Scan(&user.Id, &user.Name, &user.UserHash, &user.SecretHash, &user.IsDeleted, &user.UpdatedAt, &user.CreateAt)
+	err := r.conn.QueryRow(sql, args...).Scan(&user.Id, &user.Name, &user.UserHash, &user.SecretHash, &user.IsDeleted, &user.UpdatedAt, &user.CreateAt)
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func GetUserByUserId(conn *sql.Tx, userId int) (*model.User, error) {
-	var user model.User
-	sql := "select * from user where id = ? and is_deleted = 0"
-	err := conn.QueryRow(sql, userId).Scan(&user.Id, &user.Name, &user.UserHash, &user.SecretHash, &user.IsDeleted, &user.UpdatedAt, &user.CreateAt)
+func (r userRepo) GetById(id int) (*model.User, error) {
+	return r.queryOne("select * from user where id = ? and is_deleted = 0", id)
+}
+
+func (r userRepo) GetByName(name string) (*model.User, error) {
+	user, err := r.queryOne("select * from user where name = ? and is_deleted = 0", name)
 	if err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			return nil, nil
+		}
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
 }
 
-func ListUserByIds(conn *sql.Tx, ids []int) (*[]model.User, error) {
+func (r userRepo) ListByIds(ids []int) (*[]model.User, error) {
 	var users []model.User
 	cnt := len(ids)
 	if cnt == 0 {
@@ -44,7 +52,7 @@ func ListUserByIds(conn *sql.Tx, ids []int) (*[]model.User, error) {
 		params[index] = (ids)[index]
 	}
 	var user model.User
-	rows, err := conn.Query(sql, params...)
+	rows, err := r.conn.Query(sql, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -57,3 +65,16 @@ func ListUserByIds(conn *sql.Tx, ids []int) (*[]model.User, error) {
 	}
 	return &users, nil
 }
+
+func (r userRepo) Create(name, userHash, secretHash string) (int, error) {
+	sql := "insert into user (name, user_hash, secret_hash) values(?, ?, ?)"
+	res, err := r.conn.Exec(sql, name, userHash, secretHash)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"database/sql"
+	"zoe/model"
+)
+
+type versionRepo struct {
+	conn *sql.Tx
+}
+
+func (r versionRepo) query(sql string, args ...interface{}) (*[]model.Version, error) {
+	var versions []model.Version
+	rows, err := r.conn.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	var version model.Version
+	for rows.Next() {
+		err = rows.Scan(&version.Id, &version.ResourceId, &version.ResourceType, &version.ResourceName, &version.Visibility,
+			&version.Content, &version.UserId, &version.RollbackFrom, &version.CreateAt,
+			&version.Encrypted)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return &versions, nil
+}
+
+func (r versionRepo) GetById(id int) (*model.Version, error) {
+	versions, err := r.query("select * from version where id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(*versions) > 0 {
+		return &(*versions)[0], nil
+	}
+	return nil, nil
+}
+
+func (r versionRepo) ListByResource(resId, resType int) (*[]model.Version, error) {
+	return r.query("select * from version where resource_id = ? and resource_type = ? order by id desc", resId, resType)
+}
+
+func (r versionRepo) Create(resName, content string, resId, resType, visibility, encrypted, userId, rollbackFrom int) (int, error) {
+	sql := "insert into version (resource_id, resource_type, resource_name, visibility, content, user_id, rollback_from, encrypted) values(?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := r.conn.Exec(sql, resId, resType, resName, visibility, content, userId, rollbackFrom, encrypted)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r versionRepo) ListEncrypted(afterId, limit int) (*[]model.Version, int, error) {
+	var total int
+	err := r.conn.QueryRow("select count(*) from version where encrypted = 1").Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	versions, err := r.query("select * from version where encrypted = 1 and id > ? order by id limit ?", afterId, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return versions, total, nil
+}
+
+func (r versionRepo) ReplaceContent(id int, oldContent, newContent string) (bool, error) {
+	sql := "update version set content = ? where id = ? and content = ?"
+	res, err := r.conn.Exec(sql, newContent, id, oldContent)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"sort"
+	"time"
+	"zoe/model"
+)
+
+type auditRepo struct {
+	d *data
+}
+
+func (r auditRepo) Create(audit *model.Audit) (int, error) {
+	id := r.d.nextId("audit")
+	record := *audit
+	record.Id = id
+	record.CreateAt = time.Now()
+	r.d.audits[id] = record
+	return id, nil
+}
+
+func (r auditRepo) List(query model.AuditQuery, resType, offset, limit int) (*[]model.Audit, int, error) {
+	var audits []model.Audit
+	for _, audit := range r.d.audits {
+		if audit.OrgId != query.OrgId ||
+			(query.UserId != 0 && audit.UserId != query.UserId) ||
+			(query.TargetUserId != 0 && audit.TargetUserId != query.TargetUserId) ||
+			(query.Action != "" && audit.Action != query.Action) ||
+			(resType != 0 && audit.ResourceType != resType) ||
+			(query.ResourceId != 0 && audit.ResourceId != query.ResourceId) ||
+			(query.Since != 0 && audit.CreateAt.Before(time.Unix(query.Since, 0))) ||
+			(query.Until != 0 && !audit.CreateAt.Before(time.Unix(query.Until, 0))) {
+			continue
+		}
+		audits = append(audits, audit)
+	}
+	sort.Slice(audits, func(i, j int) bool { return audits[i].Id > audits[j].Id })
+	total := len(audits)
+	page := []model.Audit{}
+	if offset < total {
+		end := offset + limit
+		if end > total {
+			end = total
+		}
+		page = audits[offset:end]
+	}
+	return &page, total, nil
+}
@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"sort"
+	"time"
+	"zoe/model"
+)
+
+type schemaRepo struct {
+	d *data
+}
+
+func (r schemaRepo) GetLatest(resId, resType int) (*model.Schema, error) {
+	schemas, _ := r.ListByResource(resId, resType)
+	if len(*schemas) > 0 {
+		return &(*schemas)[0], nil
+	}
+	return nil, nil
+}
+
+func (r schemaRepo) ListByResource(resId, resType int) (*[]model.Schema, error) {
+	var schemas []model.Schema
+	for _, schema := range r.d.schemas {
+		if schema.ResourceId == resId && schema.ResourceType == resType {
+			schemas = append(schemas, schema)
+		}
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Version > schemas[j].Version })
+	return &schemas, nil
+}
+
+func (r schemaRepo) Create(resId, resType, version int, content string, userId int) (int, error) {
+	id := r.d.nextId("content_schema")
+	r.d.schemas[id] = model.Schema{Id: id, ResourceId: resId, ResourceType: resType, Version: version,
+		Content: content, UserId: userId, CreateAt: time.Now()}
+	return id, nil
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"time"
+	"zoe/model"
+)
+
+type draftRepo struct {
+	d *data
+}
+
+func (r draftRepo) GetByItemId(itemId int) (*model.Draft, error) {
+	for _, draft := range r.d.drafts {
+		if draft.ItemId == itemId && draft.IsDeleted == 0 {
+			return &draft, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r draftRepo) Save(itemId int, content string, userId int) error {
+	now := time.Now()
+	draft, err := r.GetByItemId(itemId)
+	if err != nil {
+		return err
+	}
+	if draft != nil {
+		draft.Content = content
+		draft.UserId = userId
+		draft.UpdatedAt = now
+		r.d.drafts[draft.Id] = *draft
+		return nil
+	}
+	id := r.d.nextId("draft")
+	r.d.drafts[id] = model.Draft{Id: id, ItemId: itemId, Content: content, UserId: userId, UpdatedAt: now, CreateAt: now}
+	return nil
+}
+
+func (r draftRepo) Delete(itemId int) error {
+	for id, draft := range r.d.drafts {
+		if draft.ItemId == itemId && draft.IsDeleted == 0 {
+			draft.IsDeleted = 1
+			draft.UpdatedAt = time.Now()
+			r.d.drafts[id] = draft
+		}
+	}
+	return nil
+}
+
+func (r draftRepo) ReplaceContent(itemId int, oldContent, newContent string) (bool, error) {
+	draft, err := r.GetByItemId(itemId)
+	if err != nil || draft == nil || draft.Content != oldContent {
+		return false, err
+	}
+	draft.Content = newContent
+	draft.UpdatedAt = time.Now()
+	r.d.drafts[draft.Id] = *draft
+	return true, nil
+}
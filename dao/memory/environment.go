@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"sort"
+	"time"
+	"zoe/model"
+)
+
+type environmentRepo struct {
+	d *data
+}
+
+func (r environmentRepo) GetByProjectIdAndName(projectId int, name string) (*model.Environment, error) {
+	for _, env := range r.d.envs {
+		if env.ProjectId == projectId && env.Name == name && env.IsDeleted == 0 {
+			return &env, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r environmentRepo) ListByProjectId(projectId int) (*[]model.Environment, error) {
+	var envs []model.Environment
+	for _, env := range r.d.envs {
+		if env.ProjectId == projectId && env.IsDeleted == 0 {
+			envs = append(envs, env)
+		}
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Id < envs[j].Id })
+	return &envs, nil
+}
+
+func (r environmentRepo) Create(projectId int, name string) (int, error) {
+	now := time.Now()
+	id := r.d.nextId("environment")
+	r.d.envs[id] = model.Environment{Id: id, ProjectId: projectId, Name: name, UpdatedAt: now, CreateAt: now}
+	return id, nil
+}
+
+func (r environmentRepo) Delete(id int) error {
+	env, ok := r.d.envs[id]
+	if !ok {
+		return nil
+	}
+	env.IsDeleted = 1
+	env.UpdatedAt = time.Now()
+	r.d.envs[id] = env
+	return nil
+}
+
+func (r environmentRepo) DeleteByProjectId(projectId int) error {
+	for id, env := range r.d.envs {
+		if env.ProjectId == projectId && env.IsDeleted == 0 {
+			env.IsDeleted = 1
+			env.UpdatedAt = time.Now()
+			r.d.envs[id] = env
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"time"
+	"zoe/basic"
+	"zoe/model"
+)
+
+type grayRepo struct {
+	d *data
+}
+
+func (r grayRepo) GetRunningByItemId(itemId int) (*model.GrayRelease, error) {
+	for _, gray := range r.d.grays {
+		if gray.ItemId == itemId && gray.Status == basic.Gray_Status_RUNNING && gray.IsDeleted == 0 {
+			return &gray, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r grayRepo) Create(itemId, versionId int, clientIds, ipRanges string, percentage, userId int) (int, error) {
+	now := time.Now()
+	id := r.d.nextId("gray_release")
+	r.d.grays[id] = model.GrayRelease{Id: id, ItemId: itemId, VersionId: versionId, ClientIds: clientIds, IpRanges: ipRanges,
+		Percentage: percentage, Status: basic.Gray_Status_RUNNING, UserId: userId, UpdatedAt: now, CreateAt: now}
+	return id, nil
+}
+
+func (r grayRepo) update(id int, fn func(gray *model.GrayRelease)) error {
+	gray, ok := r.d.grays[id]
+	if !ok || gray.IsDeleted == 1 {
+		return nil
+	}
+	fn(&gray)
+	gray.UpdatedAt = time.Now()
+	r.d.grays[id] = gray
+	return nil
+}
+
+func (r grayRepo) UpdateRule(id int, clientIds, ipRanges string, percentage int) error {
+	return r.update(id, func(gray *model.GrayRelease) {
+		gray.ClientIds = clientIds
+		gray.IpRanges = ipRanges
+		gray.Percentage = percentage
+	})
+}
+
+func (r grayRepo) UpdateStatus(id, status int) error {
+	return r.update(id, func(gray *model.GrayRelease) { gray.Status = status })
+}
+
+func (r grayRepo) DeleteByItemId(itemId int) error {
+	for id, gray := range r.d.grays {
+		if gray.ItemId == itemId && gray.IsDeleted == 0 {
+			_ = r.update(id, func(gray *model.GrayRelease) { gray.IsDeleted = 1 })
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"sort"
+	"time"
+	"zoe/model"
+)
+
+type itemRepo struct {
+	d *data
+}
+
+func (r itemRepo) GetById(id int) (*model.Item, error) {
+	item, ok := r.d.items[id]
+	if !ok || item.IsDeleted == 1 {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (r itemRepo) GetByParentIdAndName(parentId int, name, environment string) (*model.Item, error) {
+	for _, item := range r.d.items {
+		if item.ParentId == parentId && item.Name == name && item.Environment == environment && item.IsDeleted == 0 {
+			return &item, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r itemRepo) ListByParentId(projectId int) (*[]model.Item, error) {
+	var items []model.Item
+	for _, item := range r.d.items {
+		if item.ParentId == projectId && item.IsDeleted == 0 {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Id < items[j].Id })
+	return &items, nil
+}
+
+func (r itemRepo) ListByParentIdAndEnvironment(projectId int, environment string) (*[]model.Item, error) {
+	var items []model.Item
+	for _, item := range r.d.items {
+		if item.ParentId == projectId && item.Environment == environment && item.IsDeleted == 0 {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Id < items[j].Id })
+	return &items, nil
+}
+
+func (r itemRepo) Create(name, content, environment, contentType string, visibility, encrypted, parentId int) (int, error) {
+	now := time.Now()
+	id := r.d.nextId("item")
+	r.d.items[id] = model.Item{Id: id, Name: name, ParentId: parentId, Visibility: visibility, Content: content,
+		UpdatedAt: now, CreateAt: now, Environment: environment, ContentType: contentType, Encrypted: encrypted}
+	return id, nil
+}
+
+func (r itemRepo) update(id int, fn func(item *model.Item)) error {
+	item, ok := r.d.items[id]
+	if !ok || item.IsDeleted == 1 {
+		return nil
+	}
+	fn(&item)
+	item.UpdatedAt = time.Now()
+	r.d.items[id] = item
+	return nil
+}
+
+func (r itemRepo) UpdateVisibility(id, visibility int) error {
+	return r.update(id, func(item *model.Item) { item.Visibility = visibility })
+}
+
+func (r itemRepo) UpdateContentType(id int, contentType string) error {
+	return r.update(id, func(item *model.Item) { item.ContentType = contentType })
+}
+
+func (r itemRepo) UpdateEncrypted(id, encrypted int) error {
+	return r.update(id, func(item *model.Item) { item.Encrypted = encrypted })
+}
+
+func (r itemRepo) UpdateContent(id int, content string) error {
+	return r.update(id, func(item *model.Item) { item.Content = content })
+}
+
+func (r itemRepo) UpdateCurrentVersionId(id, versionId int) error {
+	return r.update(id, func(item *model.Item) { item.CurrentVersionId = versionId })
+}
+
+func (r itemRepo) Delete(id int) error {
+	return r.update(id, func(item *model.Item) { item.IsDeleted = 1 })
+}
+
+func (r itemRepo) ListEncrypted(afterId, limit int) (*[]model.Item, int, error) {
+	var items []model.Item
+	total := 0
+	for _, item := range r.d.items {
+		if item.Encrypted != 1 {
+			continue
+		}
+		total++
+		if item.Id > afterId {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Id < items[j].Id })
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return &items, total, nil
+}
+
+func (r itemRepo) ReplaceContent(id int, oldContent, newContent string) (bool, error) {
+	item, ok := r.d.items[id]
+	if !ok || item.Content != oldContent {
+		return false, nil
+	}
+	item.Content = newContent
+	item.UpdatedAt = time.Now()
+	r.d.items[id] = item
+	return true, nil
+}
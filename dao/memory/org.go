@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/model"
+)
+
+type orgRepo struct {
+	d *data
+}
+
+func (r orgRepo) GetById(id int) (*model.Org, error) {
+	org, ok := r.d.orgs[id]
+	if !ok || org.IsDeleted == 1 {
+		return nil, nil
+	}
+	return &org, nil
+}
+
+func (r orgRepo) GetByName(name string) (*model.Org, error) {
+	for _, org := range r.d.orgs {
+		if org.Name == name && org.IsDeleted == 0 {
+			return &org, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r orgRepo) ListByNames(names []string) (*[]model.Org, error) {
+	nameSet := make(map[string]bool, len(names))
+	for _, name := range names {
+		nameSet[name] = true
+	}
+	var orgs []model.Org
+	for _, org := range r.d.orgs {
+		if nameSet[org.Name] && org.IsDeleted == 0 {
+			orgs = append(orgs, org)
+		}
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].Id < orgs[j].Id })
+	return &orgs, nil
+}
+
+// 项目和item上的授权通过所属项目找到组织
+func (r orgRepo) userOrgIds(userHash string) map[int]bool {
+	orgIds := make(map[int]bool)
+	for _, privilege := range r.d.privileges {
+		if privilege.IsDeleted == 1 || privilege.UserHash != userHash ||
+			(privilege.PrivilegeType != basic.Privilege_Type_VIEWER && privilege.PrivilegeType != basic.Privilege_Type_MODIFIER) {
+			continue
+		}
+		switch privilege.ResourceType {
+		case basic.Resource_Type_ORG:
+			orgIds[privilege.ResourceId] = true
+		case basic.Resource_Type_PROJECT:
+			if project, ok := r.d.projects[privilege.ResourceId]; ok {
+				orgIds[project.ParentId] = true
+			}
+		case basic.Resource_Type_ITEM:
+			if item, ok := r.d.items[privilege.ResourceId]; ok {
+				if project, ok := r.d.projects[item.ParentId]; ok {
+					orgIds[project.ParentId] = true
+				}
+			}
+		}
+	}
+	return orgIds
+}
+
+func (r orgRepo) ListPageByUserHash(userHash string, orgId int, query repo.PageQuery, offset, limit int) (*[]model.Org, int, error) {
+	orgIds := r.userOrgIds(userHash)
+	var orgs []model.Org
+	for _, org := range r.d.orgs {
+		if org.IsDeleted == 0 && orgIds[org.Id] && (orgId == 0 || org.Id == orgId) && matchName(org.Name, query) {
+			orgs = append(orgs, org)
+		}
+	}
+	sort.Slice(orgs, func(i, j int) bool {
+		a, b := orgs[i], orgs[j]
+		compare := 0
+		switch query.Sort {
+		case "name":
+			compare = strings.Compare(a.Name, b.Name)
+		case "created_at":
+			compare = compareTime(a.CreateAt, b.CreateAt)
+		}
+		return pageLess(compare, a.Id, b.Id, query.Desc)
+	})
+	start, end := pageRange(len(orgs), offset, limit)
+	page := orgs[start:end]
+	return &page, len(orgs), nil
+}
+
+func (r orgRepo) Create(name string, visibility int) (int, error) {
+	now := time.Now()
+	id := r.d.nextId("org")
+	r.d.orgs[id] = model.Org{Id: id, Name: name, Visibility: visibility, UpdatedAt: now, CreateAt: now}
+	return id, nil
+}
+
+func (r orgRepo) update(id int, fn func(org *model.Org)) error {
+	org, ok := r.d.orgs[id]
+	if !ok || org.IsDeleted == 1 {
+		return nil
+	}
+	fn(&org)
+	org.UpdatedAt = time.Now()
+	r.d.orgs[id] = org
+	return nil
+}
+
+func (r orgRepo) UpdateVisibility(id, visibility int) error {
+	return r.update(id, func(org *model.Org) { org.Visibility = visibility })
+}
+
+func (r orgRepo) UpdateCurrentVersionId(id, versionId int) error {
+	return r.update(id, func(org *model.Org) { org.CurrentVersionId = versionId })
+}
+
+func (r orgRepo) Delete(id int) error {
+	return r.update(id, func(org *model.Org) { org.IsDeleted = 1 })
+}
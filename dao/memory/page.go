@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"strings"
+	"time"
+	"zoe/dao/repo"
+)
+
+// 与数据库实现一致: 不区分大小写按子串匹配, 有前缀时只匹配前缀之后的部分
+func matchName(name string, query repo.PageQuery) bool {
+	if query.Name == "" {
+		return true
+	}
+	name = strings.ToLower(name)
+	prefix := strings.ToLower(query.NamePrefix)
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	return strings.Contains(name[len(prefix):], strings.ToLower(query.Name))
+}
+
+// compare为排序字段的比较结果, 相同时按id排序, 方向与Desc一致
+func pageLess(compare, id, otherId int, desc bool) bool {
+	if compare == 0 {
+		compare = compareInt(id, otherId)
+	}
+	if desc {
+		return compare > 0
+	}
+	return compare < 0
+}
+
+func compareInt(a, b int) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareTime(a, b time.Time) int {
+	if a.Before(b) {
+		return -1
+	} else if a.After(b) {
+		return 1
+	}
+	return 0
+}
+
+func pageRange(total, offset, limit int) (int, int) {
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
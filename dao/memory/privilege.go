@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/model"
+)
+
+type privilegeRepo struct {
+	d *data
+}
+
+func (r privilegeRepo) list(match func(privilege *model.Privilege) bool) *[]model.Privilege {
+	var privileges []model.Privilege
+	for _, privilege := range r.d.privileges {
+		if privilege.IsDeleted == 0 && match(&privilege) {
+			privileges = append(privileges, privilege)
+		}
+	}
+	sort.Slice(privileges, func(i, j int) bool { return privileges[i].Id < privileges[j].Id })
+	return &privileges
+}
+
+func (r privilegeRepo) update(match func(privilege *model.Privilege) bool, fn func(privilege *model.Privilege)) {
+	for id, privilege := range r.d.privileges {
+		if privilege.IsDeleted == 0 && match(&privilege) {
+			fn(&privilege)
+			privilege.UpdatedAt = time.Now()
+			r.d.privileges[id] = privilege
+		}
+	}
+}
+
+func (r privilegeRepo) Get(userHash, environment string, resId, resType int) (*model.Privilege, error) {
+	privileges := r.list(func(privilege *model.Privilege) bool {
+		return privilege.UserHash == userHash && privilege.ResourceId == resId && privilege.ResourceType == resType &&
+			privilege.Environment == environment
+	})
+	if len(*privileges) > 0 {
+		return &(*privileges)[0], nil
+	}
+	return nil, nil
+}
+
+func (r privilegeRepo) ListByUserHash(userHash string) (*[]model.Privilege, error) {
+	return r.list(func(privilege *model.Privilege) bool {
+		return privilege.UserHash == userHash &&
+			(privilege.ResourceType == basic.Resource_Type_ORG || privilege.ResourceType == basic.Resource_Type_PROJECT ||
+				privilege.ResourceType == basic.Resource_Type_ITEM) &&
+			(privilege.PrivilegeType == basic.Privilege_Type_MODIFIER || privilege.PrivilegeType == basic.Privilege_Type_VIEWER)
+	}), nil
+}
+
+func (r privilegeRepo) ListByResource(resId, resType int) (*[]model.Privilege, error) {
+	return r.list(func(privilege *model.Privilege) bool {
+		return privilege.ResourceId == resId && privilege.ResourceType == resType
+	}), nil
+}
+
+func (r privilegeRepo) ListPageByResource(resId, resType int, query repo.PageQuery, offset, limit int) (*[]model.Privilege, int, error) {
+	userName := func(privilege *model.Privilege) string {
+		return r.d.users[privilege.UserId].Name
+	}
+	privileges := *r.list(func(privilege *model.Privilege) bool {
+		return privilege.ResourceId == resId && privilege.ResourceType == resType && matchName(userName(privilege), query)
+	})
+	sort.Slice(privileges, func(i, j int) bool {
+		a, b := privileges[i], privileges[j]
+		compare := 0
+		switch query.Sort {
+		case "user_name":
+			compare = strings.Compare(userName(&a), userName(&b))
+		case "type":
+			compare = compareInt(a.PrivilegeType, b.PrivilegeType)
+		case "created_at":
+			compare = compareTime(a.CreateAt, b.CreateAt)
+		}
+		return pageLess(compare, a.Id, b.Id, query.Desc)
+	})
+	start, end := pageRange(len(privileges), offset, limit)
+	page := privileges[start:end]
+	return &page, len(privileges), nil
+}
+
+func (r privilegeRepo) ListByResources(userHash string, resIds map[int]int) (*[]model.Privilege, error) {
+	return r.list(func(privilege *model.Privilege) bool {
+		resId, ok := resIds[privilege.ResourceType]
+		return privilege.UserHash == userHash && ok && privilege.ResourceId == resId
+	}), nil
+}
+
+func (r privilegeRepo) Create(userHash, resName, environment string, resId, resType, userId, priType, resVisibility int) error {
+	now := time.Now()
+	id := r.d.nextId("privilege")
+	r.d.privileges[id] = model.Privilege{Id: id, ResourceId: resId, ResourceName: resName, ResourceType: resType,
+		ResourceVisibility: resVisibility, UserId: userId, UserHash: userHash, PrivilegeType: priType, UpdatedAt: now, CreateAt: now,
+		Environment: environment}
+	return nil
+}
+
+func (r privilegeRepo) Update(userHash, environment string, priType, resId, resType int) error {
+	r.update(func(privilege *model.Privilege) bool {
+		return privilege.UserHash == userHash && privilege.ResourceId == resId && privilege.ResourceType == resType &&
+			privilege.Environment == environment
+	}, func(privilege *model.Privilege) { privilege.PrivilegeType = priType })
+	return nil
+}
+
+func (r privilegeRepo) Delete(userHash, environment string, resId, resType int) error {
+	r.update(func(privilege *model.Privilege) bool {
+		return privilege.UserHash == userHash && privilege.ResourceId == resId && privilege.ResourceType == resType &&
+			privilege.Environment == environment
+	}, func(privilege *model.Privilege) { privilege.IsDeleted = 1 })
+	return nil
+}
+
+func (r privilegeRepo) DeleteByResource(resId, resType int) error {
+	r.update(func(privilege *model.Privilege) bool {
+		return privilege.ResourceId == resId && privilege.ResourceType == resType
+	}, func(privilege *model.Privilege) { privilege.IsDeleted = 1 })
+	return nil
+}
+
+func (r privilegeRepo) DeleteByResourceAndEnvironment(resId, resType int, environment string) error {
+	r.update(func(privilege *model.Privilege) bool {
+		return privilege.ResourceId == resId && privilege.ResourceType == resType && privilege.Environment == environment
+	}, func(privilege *model.Privilege) { privilege.IsDeleted = 1 })
+	return nil
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"zoe/dao/repo"
+	"zoe/model"
+)
+
+type projectRepo struct {
+	d *data
+}
+
+func (r projectRepo) GetById(id int) (*model.Project, error) {
+	project, ok := r.d.projects[id]
+	if !ok || project.IsDeleted == 1 {
+		return nil, nil
+	}
+	return &project, nil
+}
+
+func (r projectRepo) GetByParentIdAndName(parentId int, name string) (*model.Project, error) {
+	for _, project := range r.d.projects {
+		if project.ParentId == parentId && project.Name == name && project.IsDeleted == 0 {
+			return &project, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r projectRepo) ListByParentId(orgId int) (*[]model.Project, error) {
+	var projects []model.Project
+	for _, project := range r.d.projects {
+		if project.ParentId == orgId && project.IsDeleted == 0 {
+			projects = append(projects, project)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Id < projects[j].Id })
+	return &projects, nil
+}
+
+func (r projectRepo) ListPageByParentId(orgId int, visibleIds []int, query repo.PageQuery, offset, limit int) (*[]model.Project, int, error) {
+	visible := make(map[int]bool, len(visibleIds))
+	for _, id := range visibleIds {
+		visible[id] = true
+	}
+	var projects []model.Project
+	for _, project := range r.d.projects {
+		if project.ParentId != orgId || project.IsDeleted == 1 || !matchName(project.Name, query) {
+			continue
+		}
+		if visibleIds != nil && project.Visibility != 1 && !visible[project.Id] {
+			continue
+		}
+		projects = append(projects, project)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		a, b := projects[i], projects[j]
+		compare := 0
+		switch query.Sort {
+		case "name":
+			compare = strings.Compare(a.Name, b.Name)
+		case "created_at":
+			compare = compareTime(a.CreateAt, b.CreateAt)
+		}
+		return pageLess(compare, a.Id, b.Id, query.Desc)
+	})
+	start, end := pageRange(len(projects), offset, limit)
+	page := projects[start:end]
+	return &page, len(projects), nil
+}
+
+func (r projectRepo) Create(name string, visibility, parentId int) (int, error) {
+	now := time.Now()
+	id := r.d.nextId("project")
+	r.d.projects[id] = model.Project{Id: id, Name: name, ParentId: parentId, Visibility: visibility, UpdatedAt: now, CreateAt: now}
+	return id, nil
+}
+
+func (r projectRepo) update(id int, fn func(project *model.Project)) error {
+	project, ok := r.d.projects[id]
+	if !ok || project.IsDeleted == 1 {
+		return nil
+	}
+	fn(&project)
+	project.UpdatedAt = time.Now()
+	r.d.projects[id] = project
+	return nil
+}
+
+func (r projectRepo) UpdateVisibility(id, visibility int) error {
+	return r.update(id, func(project *model.Project) { project.Visibility = visibility })
+}
+
+func (r projectRepo) UpdateCurrentVersionId(id, versionId int) error {
+	return r.update(id, func(project *model.Project) { project.CurrentVersionId = versionId })
+}
+
+func (r projectRepo) Delete(id int) error {
+	return r.update(id, func(project *model.Project) { project.IsDeleted = 1 })
+}
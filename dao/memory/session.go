@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"time"
+	"zoe/model"
+)
+
+type sessionRepo struct {
+	d *data
+}
+
+func (r sessionRepo) GetByTokenHash(tokenHash string) (*model.Session, error) {
+	now := time.Now()
+	for _, session := range r.d.sessions {
+		if session.TokenHash == tokenHash && session.ExpiredAt.After(now) && session.IsDeleted == 0 {
+			return &session, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r sessionRepo) Create(userId int, tokenHash string, expiredAt time.Time) (int, error) {
+	now := time.Now()
+	id := r.d.nextId("session")
+	r.d.sessions[id] = model.Session{Id: id, UserId: userId, TokenHash: tokenHash, ExpiredAt: expiredAt, UpdatedAt: now, CreateAt: now}
+	return id, nil
+}
+
+func (r sessionRepo) Delete(tokenHash string) error {
+	for id, session := range r.d.sessions {
+		if session.TokenHash == tokenHash && session.IsDeleted == 0 {
+			session.IsDeleted = 1
+			session.UpdatedAt = time.Now()
+			r.d.sessions[id] = session
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"database/sql"
+	"sync"
+	"zoe/config"
+	"zoe/dao/repo"
+	"zoe/model"
+)
+
+func init() {
+	repo.Register("memory", Open)
+}
+
+type data struct {
+	lastIds    map[string]int
+	orgs       map[int]model.Org
+	projects   map[int]model.Project
+	items      map[int]model.Item
+	versions   map[int]model.Version
+	drafts     map[int]model.Draft
+	grays      map[int]model.GrayRelease
+	privileges map[int]model.Privilege
+	envs       map[int]model.Environment
+	schemas    map[int]model.Schema
+	users      map[int]model.User
+	sessions   map[int]model.Session
+	tokens     map[int]model.Token
+	audits     map[int]model.Audit
+}
+
+func newData() *data {
+	return &data{
+		lastIds:    make(map[string]int),
+		orgs:       make(map[int]model.Org),
+		projects:   make(map[int]model.Project),
+		items:      make(map[int]model.Item),
+		versions:   make(map[int]model.Version),
+		drafts:     make(map[int]model.Draft),
+		grays:      make(map[int]model.GrayRelease),
+		privileges: make(map[int]model.Privilege),
+		envs:       make(map[int]model.Environment),
+		schemas:    make(map[int]model.Schema),
+		users:      make(map[int]model.User),
+		sessions:   make(map[int]model.Session),
+		tokens:     make(map[int]model.Token),
+		audits:     make(map[int]model.Audit),
+	}
+}
+
+func (d *data) clone() *data {
+	c := newData()
+	for k, v := range d.lastIds {
+		c.lastIds[k] = v
+	}
+	for k, v := range d.orgs {
+		c.orgs[k] = v
+	}
+	for k, v := range d.projects {
+		c.projects[k] = v
+	}
+	for k, v := range d.items {
+		c.items[k] = v
+	}
+	for k, v := range d.versions {
+		c.versions[k] = v
+	}
+	for k, v := range d.drafts {
+		c.drafts[k] = v
+	}
+	for k, v := range d.grays {
+		c.grays[k] = v
+	}
+	for k, v := range d.privileges {
+		c.privileges[k] = v
+	}
+	for k, v := range d.envs {
+		c.envs[k] = v
+	}
+	for k, v := range d.schemas {
+		c.schemas[k] = v
+	}
+	for k, v := range d.users {
+		c.users[k] = v
+	}
+	for k, v := range d.sessions {
+		c.sessions[k] = v
+	}
+	for k, v := range d.tokens {
+		c.tokens[k] = v
+	}
+	for k, v := range d.audits {
+		c.audits[k] = v
+	}
+	return c
+}
+
+// 模拟自增主键
+func (d *data) nextId(table string) int {
+	d.lastIds[table]++
+	return d.lastIds[table]
+}
+
+// 进程内存储, 数据不落盘, 用于测试和本地调试.
+// 同一时刻只允许一个事务, Begin时保存快照, Rollback时恢复快照
+type store struct {
+	mu   sync.Mutex
+	data *data
+}
+
+func Open(c *config.Config) (repo.Store, error) {
+	return NewStore(), nil
+}
+
+func NewStore() repo.Store {
+	return &store{data: newData()}
+}
+
+func (s *store) Begin() (repo.Tx, error) {
+	s.mu.Lock()
+	return &tx{store: s, snapshot: s.data.clone()}, nil
+}
+
+func (s *store) Close() error {
+	return nil
+}
+
+type tx struct {
+	store    *store
+	snapshot *data
+	done     bool
+}
+
+func (t *tx) Commit() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	t.store.mu.Unlock()
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	t.store.data = t.snapshot
+	t.store.mu.Unlock()
+	return nil
+}
+
+func (t *tx) Orgs() repo.OrgRepository {
+	return orgRepo{d: t.store.data}
+}
+
+func (t *tx) Projects() repo.ProjectRepository {
+	return projectRepo{d: t.store.data}
+}
+
+func (t *tx) Items() repo.ItemRepository {
+	return itemRepo{d: t.store.data}
+}
+
+func (t *tx) Versions() repo.VersionRepository {
+	return versionRepo{d: t.store.data}
+}
+
+func (t *tx) Drafts() repo.DraftRepository {
+	return draftRepo{d: t.store.data}
+}
+
+func (t *tx) Grays() repo.GrayRepository {
+	return grayRepo{d: t.store.data}
+}
+
+func (t *tx) Privileges() repo.PrivilegeRepository {
+	return privilegeRepo{d: t.store.data}
+}
+
+func (t *tx) Environments() repo.EnvironmentRepository {
+	return environmentRepo{d: t.store.data}
+}
+
+func (t *tx) Schemas() repo.SchemaRepository {
+	return schemaRepo{d: t.store.data}
+}
+
+func (t *tx) Users() repo.UserRepository {
+	return userRepo{d: t.store.data}
+}
+
+func (t *tx) Sessions() repo.SessionRepository {
+	return sessionRepo{d: t.store.data}
+}
+
+func (t *tx) Tokens() repo.TokenRepository {
+	return tokenRepo{d: t.store.data}
+}
+
+func (t *tx) Audits() repo.AuditRepository {
+	return auditRepo{d: t.store.data}
+}
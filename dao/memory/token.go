@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"sort"
+	"time"
+	"zoe/model"
+)
+
+type tokenRepo struct {
+	d *data
+}
+
+func (r tokenRepo) GetByTokenHash(tokenHash string) (*model.Token, error) {
+	now := time.Now()
+	for _, token := range r.d.tokens {
+		if token.TokenHash == tokenHash && token.ExpiredAt.After(now) && token.IsDeleted == 0 {
+			return &token, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r tokenRepo) GetById(id int) (*model.Token, error) {
+	token, ok := r.d.tokens[id]
+	if !ok || token.IsDeleted == 1 {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+func (r tokenRepo) ListByUserId(userId int) (*[]model.Token, error) {
+	var tokens []model.Token
+	for _, token := range r.d.tokens {
+		if token.UserId == userId && token.IsDeleted == 0 {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Id > tokens[j].Id })
+	return &tokens, nil
+}
+
+func (r tokenRepo) Create(userId int, name, tokenHash string, readOnly, resType, resId int, expiredAt time.Time) (int, error) {
+	now := time.Now()
+	id := r.d.nextId("token")
+	r.d.tokens[id] = model.Token{Id: id, UserId: userId, Name: name, TokenHash: tokenHash, ReadOnly: readOnly,
+		ResourceType: resType, ResourceId: resId, ExpiredAt: expiredAt, UpdatedAt: now, CreateAt: now}
+	return id, nil
+}
+
+func (r tokenRepo) UpdateLastUsedAt(id int) error {
+	token, ok := r.d.tokens[id]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	token.LastUsedAt = &now
+	token.UpdatedAt = now
+	r.d.tokens[id] = token
+	return nil
+}
+
+func (r tokenRepo) Delete(id int) error {
+	token, ok := r.d.tokens[id]
+	if !ok || token.IsDeleted == 1 {
+		return nil
+	}
+	token.IsDeleted = 1
+	token.UpdatedAt = time.Now()
+	r.d.tokens[id] = token
+	return nil
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"sort"
+	"time"
+	"zoe/dao/repo"
+	"zoe/model"
+)
+
+type userRepo struct {
+	d *data
+}
+
+func (r userRepo) find(match func(user *model.User) bool) *model.User {
+	for _, user := range r.d.users {
+		if user.IsDeleted == 0 && match(&user) {
+			return &user
+		}
+	}
+	return nil
+}
+
+func (r userRepo) GetById(id int) (*model.User, error) {
+	user := r.find(func(user *model.User) bool { return user.Id == id })
+	if user == nil {
+		return nil, repo.ErrNotFound
+	}
+	return user, nil
+}
+
+func (r userRepo) GetByName(name string) (*model.User, error) {
+	return r.find(func(user *model.User) bool { return user.Name == name }), nil
+}
+
+func (r userRepo) ListByIds(ids []int) (*[]model.User, error) {
+	var users []model.User
+	idSet := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		idSet[id] = true
+	}
+	for _, user := range r.d.users {
+		if idSet[user.Id] && user.IsDeleted == 0 {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Id < users[j].Id })
+	return &users, nil
+}
+
+func (r userRepo) Create(name, userHash, secretHash string) (int, error) {
+	now := time.Now()
+	id := r.d.nextId("user")
+	r.d.users[id] = model.User{Id: id, Name: name, UserHash: userHash, SecretHash: secretHash, UpdatedAt: now, CreateAt: now}
+	return id, nil
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"sort"
+	"time"
+	"zoe/model"
+)
+
+type versionRepo struct {
+	d *data
+}
+
+func (r versionRepo) GetById(id int) (*model.Version, error) {
+	version, ok := r.d.versions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &version, nil
+}
+
+func (r versionRepo) ListByResource(resId, resType int) (*[]model.Version, error) {
+	var versions []model.Version
+	for _, version := range r.d.versions {
+		if version.ResourceId == resId && version.ResourceType == resType {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Id > versions[j].Id })
+	return &versions, nil
+}
+
+func (r versionRepo) Create(resName, content string, resId, resType, visibility, encrypted, userId, rollbackFrom int) (int, error) {
+	id := r.d.nextId("version")
+	r.d.versions[id] = model.Version{Id: id, ResourceId: resId, ResourceType: resType, ResourceName: resName,
+		Visibility: visibility, Content: content, UserId: userId, RollbackFrom: rollbackFrom, CreateAt: time.Now(),
+		Encrypted: encrypted}
+	return id, nil
+}
+
+func (r versionRepo) ListEncrypted(afterId, limit int) (*[]model.Version, int, error) {
+	var versions []model.Version
+	total := 0
+	for _, version := range r.d.versions {
+		if version.Encrypted != 1 {
+			continue
+		}
+		total++
+		if version.Id > afterId {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Id < versions[j].Id })
+	if len(versions) > limit {
+		versions = versions[:limit]
+	}
+	return &versions, total, nil
+}
+
+func (r versionRepo) ReplaceContent(id int, oldContent, newContent string) (bool, error) {
+	version, ok := r.d.versions[id]
+	if !ok || version.Content != oldContent {
+		return false, nil
+	}
+	version.Content = newContent
+	r.d.versions[id] = version
+	return true, nil
+}
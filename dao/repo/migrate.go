@@ -0,0 +1,36 @@
+package repo
+
+import "errors"
+
+type Migration struct {
+	Version int
+	Name    string
+}
+
+// 有表结构的存储引擎实现该接口, 按版本号升级或回退表结构; memory引擎没有表结构, 不实现
+type Migrator interface {
+	// 按版本号升序返回全部迁移
+	Migrations() []Migration
+	// 当前已应用的最高版本, 从未迁移过时为0
+	SchemaVersion() (int, error)
+	// 逐个应用或回退迁移直到version, 每完成一个迁移调用一次progress
+	MigrateTo(version int, progress func(m Migration, up bool)) error
+}
+
+var ErrMigrateNotSupported = errors.New("当前存储引擎不支持结构迁移")
+
+func GetMigrator() (Migrator, error) {
+	migrator, ok := DB.(Migrator)
+	if !ok {
+		return nil, ErrMigrateNotSupported
+	}
+	return migrator, nil
+}
+
+func LatestVersion(migrator Migrator) int {
+	migrations := migrator.Migrations()
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
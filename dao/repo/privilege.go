@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"zoe/basic"
+	"zoe/model"
+)
+
+// 已有授权时只会提升, 不会降低
+func AddWithCheck(tx Tx, userHash, resName string, resId, resType, userId, priType, resVisibility int) error {
+	privilege, err := tx.Privileges().Get(userHash, "", resId, resType)
+	if err != nil {
+		return err
+	}
+	if privilege == nil {
+		return tx.Privileges().Create(userHash, resName, "", resId, resType, userId, priType, resVisibility)
+	}
+	if privilege.PrivilegeType >= priType {
+		return nil
+	}
+	return tx.Privileges().Update(userHash, "", priType, resId, resType)
+}
+
+func ValidateForUserModifyOrg(tx Tx, user *model.User, orgId int) (bool, error) {
+	priType, err := ResolvePrivilege(tx, user, orgId, basic.Resource_Type_ORG)
+	if err != nil {
+		return false, err
+	}
+	return priType >= basic.Privilege_Type_MODIFIER, nil
+}
+
+func ValidateForUserViewOrg(tx Tx, user *model.User, orgId int) (bool, error) {
+	priType, err := ResolvePrivilege(tx, user, orgId, basic.Resource_Type_ORG)
+	if err != nil {
+		return false, err
+	}
+	return priType >= basic.Privilege_Type_VIEWER, nil
+}
+
+func ValidateUserForProjectCreation(tx Tx, user *model.User, orgId int) (bool, error) {
+	flag, err := ValidateForUserViewOrg(tx, user, orgId)
+	if err != nil {
+		return false, err
+	}
+	return flag, nil
+}
+
+func ValidateForUserModifyProject(tx Tx, user *model.User, projectId int) (bool, error) {
+	priType, err := ResolvePrivilege(tx, user, projectId, basic.Resource_Type_PROJECT)
+	if err != nil {
+		return false, err
+	}
+	return priType >= basic.Privilege_Type_MODIFIER, nil
+}
+
+// 在项目的某个环境下创建或修改item, 限定在该环境的授权也生效
+func ValidateForUserModifyProjectInEnvironment(tx Tx, user *model.User, projectId int, environment string) (bool, error) {
+	priType, err := ResolvePrivilegeInEnvironment(tx, user, projectId, basic.Resource_Type_PROJECT, environment)
+	if err != nil {
+		return false, err
+	}
+	return priType >= basic.Privilege_Type_MODIFIER, nil
+}
+
+// 能查看org/project的任一环境即可看到该资源本身, 其中的item仍按各自的环境判断
+func ValidateForUserViewAnyEnvironment(tx Tx, user *model.User, resId, resType int) (bool, error) {
+	privileges, _, err := listGrants(tx, user, resId, resType)
+	if err != nil {
+		return false, err
+	}
+	for _, privilege := range *privileges {
+		if privilege.PrivilegeType >= basic.Privilege_Type_VIEWER {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func ValidateForUserViewProject(tx Tx, user *model.User, projectId int) (bool, error) {
+	priType, err := ResolvePrivilege(tx, user, projectId, basic.Resource_Type_PROJECT)
+	if err != nil {
+		return false, err
+	}
+	return priType >= basic.Privilege_Type_VIEWER, nil
+}
+
+func ValidateForUserModifyItem(tx Tx, user *model.User, itemId int) (bool, error) {
+	priType, err := ResolvePrivilege(tx, user, itemId, basic.Resource_Type_ITEM)
+	if err != nil {
+		return false, err
+	}
+	return priType >= basic.Privilege_Type_MODIFIER, nil
+}
+
+func ValidateForUserPullItem(tx Tx, user *model.User, itemId int) (bool, error) {
+	priType, err := ResolvePrivilege(tx, user, itemId, basic.Resource_Type_ITEM)
+	if err != nil {
+		return false, err
+	}
+	return priType >= basic.Privilege_Type_PULLER, nil
+}
+
+// 加密item只对拉取方和修改者解密, 只读用户不能通过拉取看到明文.
+// 只读token只限制写操作, 因此这里按授权本身判断, 仍受token范围限制
+func ValidateForUserPullSecretItem(tx Tx, user *model.User, itemId int) (bool, error) {
+	priType, err := resolveGrant(tx, user, itemId, basic.Resource_Type_ITEM, "")
+	if err != nil {
+		return false, err
+	}
+	return priType == basic.Privilege_Type_PULLER || priType >= basic.Privilege_Type_MODIFIER, nil
+}
+
+func ValidateForUserViewItem(tx Tx, user *model.User, itemId int) (bool, error) {
+	priType, err := ResolvePrivilege(tx, user, itemId, basic.Resource_Type_ITEM)
+	if err != nil {
+		return false, err
+	}
+	return priType >= basic.Privilege_Type_VIEWER, nil
+}
+
+// 计算用户对org/project/item的有效权限: 取资源自身及其上级project、org的授权中最高的一个,
+// 没有任何授权时返回Privilege_Type_NONE. 限定了环境的授权只对该环境下的item生效.
+// 通过API token认证时, 结果还受token的范围和只读限制
+func ResolvePrivilege(tx Tx, user *model.User, resId, resType int) (int, error) {
+	return ResolvePrivilegeInEnvironment(tx, user, resId, resType, "")
+}
+
+// 同ResolvePrivilege, 但对org/project按指定环境计算, 限定在该环境的授权也生效; item总是按自身的环境计算
+func ResolvePrivilegeInEnvironment(tx Tx, user *model.User, resId, resType int, environment string) (int, error) {
+	priType, err := resolveGrant(tx, user, resId, resType, environment)
+	if err != nil {
+		return basic.Privilege_Type_NONE, err
+	}
+	if user != nil && user.Token != nil && user.Token.ReadOnly == 1 && priType > basic.Privilege_Type_VIEWER {
+		priType = basic.Privilege_Type_VIEWER
+	}
+	return priType, nil
+}
+
+// 用户实际获得的授权, 只受token范围限制, 不受只读限制
+func resolveGrant(tx Tx, user *model.User, resId, resType int, environment string) (int, error) {
+	privileges, itemEnvironment, err := listGrants(tx, user, resId, resType)
+	if err != nil {
+		return basic.Privilege_Type_NONE, err
+	}
+	if resType == basic.Resource_Type_ITEM {
+		environment = itemEnvironment
+	}
+	priType := basic.Privilege_Type_NONE
+	for _, privilege := range *privileges {
+		if privilege.Environment != "" && privilege.Environment != environment {
+			continue
+		}
+		if privilege.PrivilegeType > priType {
+			priType = privilege.PrivilegeType
+		}
+	}
+	return priType, nil
+}
+
+// 用户在资源及其上级project、org上的全部授权(不区分环境), 资源为item时同时返回item的环境
+func listGrants(tx Tx, user *model.User, resId, resType int) (*[]model.Privilege, string, error) {
+	if user == nil {
+		return &[]model.Privilege{}, "", nil
+	}
+	resIds := map[int]int{resType: resId}
+	environment := ""
+	if resType == basic.Resource_Type_ITEM {
+		item, err := tx.Items().GetById(resId)
+		if err != nil {
+			return nil, "", err
+		}
+		if item == nil {
+			return &[]model.Privilege{}, "", nil
+		}
+		resIds[basic.Resource_Type_PROJECT] = item.ParentId
+		environment = item.Environment
+	}
+	if projectId, ok := resIds[basic.Resource_Type_PROJECT]; ok {
+		project, err := tx.Projects().GetById(projectId)
+		if err != nil {
+			return nil, "", err
+		}
+		if project == nil {
+			return &[]model.Privilege{}, "", nil
+		}
+		resIds[basic.Resource_Type_ORG] = project.ParentId
+	}
+	token := user.Token
+	if token != nil && token.ResourceType != 0 && resIds[token.ResourceType] != token.ResourceId {
+		return &[]model.Privilege{}, "", nil
+	}
+	privileges, err := tx.Privileges().ListByResources(user.UserHash, resIds)
+	if err != nil {
+		return nil, "", err
+	}
+	return privileges, environment, nil
+}
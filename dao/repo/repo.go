@@ -0,0 +1,212 @@
+package repo
+
+import (
+	"errors"
+	"time"
+	"zoe/config"
+	"zoe/model"
+)
+
+// 按id或唯一键查询单条记录时, 记录不存在返回nil, nil; 用户查询沿用原有约定, 不存在时返回error
+
+// 分页列表的查询条件. Name按名称子串匹配, 不区分大小写; NamePrefix不为空时只匹配名称中该前缀之后的部分.
+// Sort为排序字段, 取值由各列表方法说明, 为空或不支持时按id排序; 排序字段相同时再按id排序, 方向与Desc一致
+type PageQuery struct {
+	NamePrefix string
+	Name       string
+	Sort       string
+	Desc       bool
+}
+
+type OrgRepository interface {
+	GetById(id int) (*model.Org, error)
+	GetByName(name string) (*model.Org, error)
+	ListByNames(names []string) (*[]model.Org, error)
+	// 用户在组织本身或其下项目、item上有viewer或modifier授权的组织, orgId不为0时只返回该组织.
+	// Sort可以是id、name或created_at, 同时返回满足条件的总数
+	ListPageByUserHash(userHash string, orgId int, query PageQuery, offset, limit int) (*[]model.Org, int, error)
+	Create(name string, visibility int) (int, error)
+	UpdateVisibility(id, visibility int) error
+	UpdateCurrentVersionId(id, versionId int) error
+	Delete(id int) error
+}
+
+type ProjectRepository interface {
+	GetById(id int) (*model.Project, error)
+	GetByParentIdAndName(parentId int, name string) (*model.Project, error)
+	ListByParentId(orgId int) (*[]model.Project, error)
+	// visibleIds不为nil时只返回公开项目和visibleIds中的项目. Sort可以是id、name或created_at, 同时返回满足条件的总数
+	ListPageByParentId(orgId int, visibleIds []int, query PageQuery, offset, limit int) (*[]model.Project, int, error)
+	Create(name string, visibility, parentId int) (int, error)
+	UpdateVisibility(id, visibility int) error
+	UpdateCurrentVersionId(id, versionId int) error
+	Delete(id int) error
+}
+
+type ItemRepository interface {
+	GetById(id int) (*model.Item, error)
+	GetByParentIdAndName(parentId int, name, environment string) (*model.Item, error)
+	ListByParentId(projectId int) (*[]model.Item, error)
+	ListByParentIdAndEnvironment(projectId int, environment string) (*[]model.Item, error)
+	Create(name, content, environment, contentType string, visibility, encrypted, parentId int) (int, error)
+	UpdateVisibility(id, visibility int) error
+	UpdateContentType(id int, contentType string) error
+	UpdateEncrypted(id, encrypted int) error
+	// 密钥轮换用: 按id升序返回afterId之后的加密item(含已删除的), 以及加密item的总数
+	ListEncrypted(afterId, limit int) (*[]model.Item, int, error)
+	// 内容仍为oldContent时才改写, 返回是否改写; 避免覆盖轮换期间并发写入的新内容
+	ReplaceContent(id int, oldContent, newContent string) (bool, error)
+	UpdateContent(id int, content string) error
+	UpdateCurrentVersionId(id, versionId int) error
+	Delete(id int) error
+}
+
+type VersionRepository interface {
+	GetById(id int) (*model.Version, error)
+	// 按id倒序返回
+	ListByResource(resId, resType int) (*[]model.Version, error)
+	Create(resName, content string, resId, resType, visibility, encrypted, userId, rollbackFrom int) (int, error)
+	// 密钥轮换用: 按id升序返回afterId之后加密保存的版本, 以及这类版本的总数
+	ListEncrypted(afterId, limit int) (*[]model.Version, int, error)
+	// 版本内容不可修改, 只有密钥轮换可以把密文改写为同一明文在新密钥下的密文
+	ReplaceContent(id int, oldContent, newContent string) (bool, error)
+}
+
+type DraftRepository interface {
+	GetByItemId(itemId int) (*model.Draft, error)
+	Save(itemId int, content string, userId int) error
+	ReplaceContent(itemId int, oldContent, newContent string) (bool, error)
+	Delete(itemId int) error
+}
+
+type GrayRepository interface {
+	GetRunningByItemId(itemId int) (*model.GrayRelease, error)
+	Create(itemId, versionId int, clientIds, ipRanges string, percentage, userId int) (int, error)
+	UpdateRule(id int, clientIds, ipRanges string, percentage int) error
+	UpdateStatus(id, status int) error
+	DeleteByItemId(itemId int) error
+}
+
+type PrivilegeRepository interface {
+	// 同一用户在同一资源上可以有多条限定在不同环境的授权, environment为空表示不限定环境的授权
+	Get(userHash, environment string, resId, resType int) (*model.Privilege, error)
+	// 用户在org/project/item上的viewer和modifier授权
+	ListByUserHash(userHash string) (*[]model.Privilege, error)
+	ListByResource(resId, resType int) (*[]model.Privilege, error)
+	// Name按被授权用户的用户名匹配. Sort可以是id、user_name、type或created_at, 同时返回满足条件的总数
+	ListPageByResource(resId, resType int, query PageQuery, offset, limit int) (*[]model.Privilege, int, error)
+	// resIds为资源类型到资源id的映射, 返回用户在其中任一资源上的授权
+	ListByResources(userHash string, resIds map[int]int) (*[]model.Privilege, error)
+	Create(userHash, resName, environment string, resId, resType, userId, priType, resVisibility int) error
+	// 修改限定在该环境的授权的类型
+	Update(userHash, environment string, priType, resId, resType int) error
+	Delete(userHash, environment string, resId, resType int) error
+	DeleteByResource(resId, resType int) error
+	// 删除限定在该环境的授权
+	DeleteByResourceAndEnvironment(resId, resType int, environment string) error
+}
+
+type EnvironmentRepository interface {
+	GetByProjectIdAndName(projectId int, name string) (*model.Environment, error)
+	ListByProjectId(projectId int) (*[]model.Environment, error)
+	Create(projectId int, name string) (int, error)
+	Delete(id int) error
+	DeleteByProjectId(projectId int) error
+}
+
+type SchemaRepository interface {
+	// 最新的版本, 从未设置过时返回nil
+	GetLatest(resId, resType int) (*model.Schema, error)
+	// 按版本倒序返回
+	ListByResource(resId, resType int) (*[]model.Schema, error)
+	Create(resId, resType, version int, content string, userId int) (int, error)
+}
+
+type UserRepository interface {
+	GetById(id int) (*model.User, error)
+	GetByName(name string) (*model.User, error)
+	ListByIds(ids []int) (*[]model.User, error)
+	Create(name, userHash, secretHash string) (int, error)
+}
+
+type SessionRepository interface {
+	// 只返回未过期的会话
+	GetByTokenHash(tokenHash string) (*model.Session, error)
+	Create(userId int, tokenHash string, expiredAt time.Time) (int, error)
+	Delete(tokenHash string) error
+}
+
+type TokenRepository interface {
+	// 只返回未过期的token
+	GetByTokenHash(tokenHash string) (*model.Token, error)
+	GetById(id int) (*model.Token, error)
+	ListByUserId(userId int) (*[]model.Token, error)
+	Create(userId int, name, tokenHash string, readOnly, resType, resId int, expiredAt time.Time) (int, error)
+	UpdateLastUsedAt(id int) error
+	Delete(id int) error
+}
+
+type AuditRepository interface {
+	Create(audit *model.Audit) (int, error)
+	// 按条件分页查询, 同时返回满足条件的总数; resType为0时不限资源类型
+	List(query model.AuditQuery, resType, offset, limit int) (*[]model.Audit, int, error)
+}
+
+// 一次事务内的所有读写都通过Tx取得的repository完成
+type Tx interface {
+	Commit() error
+	Rollback() error
+	Orgs() OrgRepository
+	Projects() ProjectRepository
+	Items() ItemRepository
+	Versions() VersionRepository
+	Drafts() DraftRepository
+	Grays() GrayRepository
+	Privileges() PrivilegeRepository
+	Environments() EnvironmentRepository
+	Schemas() SchemaRepository
+	Users() UserRepository
+	Sessions() SessionRepository
+	Tokens() TokenRepository
+	Audits() AuditRepository
+}
+
+type Store interface {
+	Begin() (Tx, error)
+	Close() error
+}
+
+type OpenFunc func(c *config.Config) (Store, error)
+
+var (
+	DB Store
+
+	ErrNotFound = errors.New("记录不存在")
+
+	engines = make(map[string]OpenFunc)
+)
+
+// 各存储实现在init中注册自己的引擎名
+func Register(engine string, open OpenFunc) {
+	engines[engine] = open
+}
+
+// 按config.Database.Engine打开存储
+func Open(c *config.Config) error {
+	open, ok := engines[c.Database.Engine]
+	if !ok {
+		return errors.New("不支持的存储引擎: " + c.Database.Engine)
+	}
+	store, err := open(c)
+	if err != nil {
+		return err
+	}
+	DB = store
+	return nil
+}
+
+func Close() {
+	if DB != nil {
+		_ = DB.Close()
+	}
+}
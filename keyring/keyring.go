@@ -0,0 +1,119 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"zoe/config"
+)
+
+var (
+	ErrNoKey      = errors.New("未配置加密密钥")
+	ErrCiphertext = errors.New("密文格式错误")
+)
+
+// 密文格式为"密钥id:base64(nonce+密文)", 轮换密钥后旧内容仍能按其中的密钥id解密
+type Keyring struct {
+	keyId string
+	keys  map[string]cipher.AEAD
+}
+
+var Keys *Keyring
+
+func InitKeyring(c *config.Config) error {
+	keys, err := NewKeyring(c.Secret.KeyId, c.Secret.Keys)
+	if err != nil {
+		return err
+	}
+	Keys = keys
+	return nil
+}
+
+func NewKeyring(keyId string, keys map[string]string) (*Keyring, error) {
+	k := &Keyring{keyId: keyId, keys: make(map[string]cipher.AEAD)}
+	for id, encoded := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("非法的密钥id: %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("密钥%s不是合法的base64: %v", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("密钥%s长度必须为32字节", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		if k.keys[id], err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	if keyId != "" && k.keys[keyId] == nil {
+		return nil, fmt.Errorf("密钥%s不存在", keyId)
+	}
+	return k, nil
+}
+
+// 当前用于加密新内容的密钥id, 未配置时为空串
+func (k *Keyring) KeyId() string {
+	if k == nil {
+		return ""
+	}
+	return k.keyId
+}
+
+func (k *Keyring) HasKey(keyId string) bool {
+	return k != nil && k.keys[keyId] != nil
+}
+
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	return k.EncryptWith(k.KeyId(), plaintext)
+}
+
+func (k *Keyring) EncryptWith(keyId, plaintext string) (string, error) {
+	if !k.HasKey(keyId) {
+		return "", ErrNoKey
+	}
+	aead := k.keys[keyId]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(keyId))
+	return keyId + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (k *Keyring) Decrypt(ciphertext string) (string, error) {
+	keyId := KeyIdOf(ciphertext)
+	if keyId == "" {
+		return "", ErrCiphertext
+	}
+	if !k.HasKey(keyId) {
+		return "", fmt.Errorf("密钥%s不存在", keyId)
+	}
+	aead := k.keys[keyId]
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext[len(keyId)+1:])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrCiphertext
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(keyId))
+	if err != nil {
+		return "", fmt.Errorf("使用密钥%s解密失败", keyId)
+	}
+	return string(plaintext), nil
+}
+
+// 返回密文使用的密钥id, 不是密文时返回空串
+func KeyIdOf(ciphertext string) string {
+	index := strings.Index(ciphertext, ":")
+	if index <= 0 {
+		return ""
+	}
+	return ciphertext[:index]
+}
@@ -0,0 +1,126 @@
+package keyring
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+var (
+	key1 = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("1", 32)))
+	key2 = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("2", 32)))
+)
+
+func newTestKeyring(t *testing.T, keyId string, keys map[string]string) *Keyring {
+	k, err := NewKeyring(keyId, keys)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return k
+}
+
+func TestNewKeyring(t *testing.T) {
+	cases := []struct {
+		name    string
+		keyId   string
+		keys    map[string]string
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"valid", "k1", map[string]string{"k1": key1, "k2": key2}, false},
+		{"missing current key", "k3", map[string]string{"k1": key1}, true},
+		{"bad base64", "k1", map[string]string{"k1": "not base64!"}, true},
+		{"short key", "k1", map[string]string{"k1": base64.StdEncoding.EncodeToString([]byte("short"))}, true},
+		{"colon in key id", "k:1", map[string]string{"k:1": key1}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewKeyring(tc.keyId, tc.keys); (err != nil) != tc.wantErr {
+				t.Errorf("NewKeyring() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	k := newTestKeyring(t, "k1", map[string]string{"k1": key1, "k2": key2})
+	cases := []struct {
+		name      string
+		keyId     string
+		plaintext string
+	}{
+		{"empty", "k1", ""},
+		{"ascii", "k1", "password=123"},
+		{"unicode", "k1", "密码: 中文\n第二行"},
+		{"old key", "k2", "a=1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ciphertext, err := k.EncryptWith(tc.keyId, tc.plaintext)
+			if err != nil {
+				t.Fatalf("EncryptWith() error = %v", err)
+			}
+			if KeyIdOf(ciphertext) != tc.keyId {
+				t.Errorf("KeyIdOf() = %q, want %q", KeyIdOf(ciphertext), tc.keyId)
+			}
+			plaintext, err := k.Decrypt(ciphertext)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if plaintext != tc.plaintext {
+				t.Errorf("Decrypt() = %q, want %q", plaintext, tc.plaintext)
+			}
+		})
+	}
+}
+
+func TestDecryptFailure(t *testing.T) {
+	k := newTestKeyring(t, "k1", map[string]string{"k1": key1, "k2": key2})
+	ciphertext, err := k.Encrypt("password=123")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, "k1:"))
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 1
+	cases := []struct {
+		name       string
+		keyring    *Keyring
+		ciphertext string
+	}{
+		{"unknown key id", k, "k3:" + strings.TrimPrefix(ciphertext, "k1:")},
+		{"wrong key id", k, "k2:" + strings.TrimPrefix(ciphertext, "k1:")},
+		{"key removed", newTestKeyring(t, "k2", map[string]string{"k2": key2}), ciphertext},
+		{"tampered ciphertext", k, "k1:" + base64.StdEncoding.EncodeToString(tampered)},
+		{"truncated", k, "k1:" + base64.StdEncoding.EncodeToString(sealed[:4])},
+		{"bad base64", k, "k1:!!!"},
+		{"no key id", k, "plaintext"},
+		{"no keyring", nil, ciphertext},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if plaintext, err := tc.keyring.Decrypt(tc.ciphertext); err == nil {
+				t.Errorf("Decrypt() = %q, want error", plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptWithoutKey(t *testing.T) {
+	cases := []struct {
+		name    string
+		keyring *Keyring
+		keyId   string
+	}{
+		{"no keyring", nil, ""},
+		{"no current key", newTestKeyring(t, "", map[string]string{"k1": key1}), ""},
+		{"unknown key id", newTestKeyring(t, "k1", map[string]string{"k1": key1}), "k2"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.keyring.EncryptWith(tc.keyId, "a=1"); err != ErrNoKey {
+				t.Errorf("EncryptWith() error = %v, want %v", err, ErrNoKey)
+			}
+		})
+	}
+}
@@ -8,10 +8,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
+	"zoe/cache"
 	"zoe/config"
 	"zoe/controller"
-	"zoe/dao/db"
+	_ "zoe/dao/db"
+	_ "zoe/dao/memory"
+	"zoe/dao/repo"
+	"zoe/keyring"
+	"zoe/middleware"
+	"zoe/service"
 )
 
 var (
@@ -36,16 +43,75 @@ func applyRoute(r *gin.Engine) {
 	v1 := r.Group("/api")
 	v1.GET("/info", InfoHandler)
 
-	v1.POST("/org", controller.CreateOrgHandler)
-	v1.PUT("/org/:org_id", controller.UpdateOrgHandler)
-	v1.DELETE("/org/:org_id", controller.DeleteOrgHandler)
-	v1.GET("/org", controller.ListOrgHandler)
-	v1.GET("/org/:org_id", controller.SingleOrgHandler)
-	v1.POST("/org/:org_id/authorize", controller.AuthorizeOrgHandler)
-	v1.DELETE("/org/:org_id/authorize/:user_id", controller.DeleteAuthorizeOrgHandler)
+	v1.POST("/user/register", controller.RegisterHandler)
+	v1.POST("/user/login", controller.LoginHandler)
 
-	v1.PUT("/project", controller.CreateProjectHandler)
-	v1.POST("/project/:project_id", controller.UpdateProjectHandler)
+	auth := v1.Group("", middleware.Authenticate())
+	auth.POST("/user/logout", controller.LogoutHandler)
+	auth.GET("/user/me", controller.CurrentUserHandler)
+	auth.POST("/token", controller.CreateTokenHandler)
+	auth.GET("/token", controller.ListTokenHandler)
+	auth.DELETE("/token/:token_id", controller.DeleteTokenHandler)
+
+	auth.POST("/org", controller.CreateOrgHandler)
+	auth.PUT("/org/:org_id", controller.UpdateOrgHandler)
+	auth.DELETE("/org/:org_id", controller.DeleteOrgHandler)
+	auth.GET("/org", controller.ListOrgHandler)
+	auth.GET("/org/:org_id", controller.SingleOrgHandler)
+	auth.GET("/org/:org_id/authorize", controller.ListAuthorizeOrgHandler)
+	auth.POST("/org/:org_id/authorize", controller.AuthorizeOrgHandler)
+	auth.DELETE("/org/:org_id/authorize/:user_id", controller.DeleteAuthorizeOrgHandler)
+	auth.GET("/org/:org_id/version", controller.ListOrgVersionHandler)
+	auth.GET("/org/:org_id/project", controller.ListProjectHandler)
+	auth.GET("/org/:org_id/export", controller.ExportOrgHandler)
+	auth.POST("/import", controller.ImportOrgHandler)
+
+	auth.PUT("/project", controller.CreateProjectHandler)
+	auth.POST("/project/:project_id", controller.UpdateProjectHandler)
+	auth.GET("/project/:project_id", controller.SingleProjectHandler)
+	auth.DELETE("/project/:project_id", controller.DeleteProjectHandler)
+	auth.GET("/project/:project_id/authorize", controller.ListAuthorizeProjectHandler)
+	auth.POST("/project/:project_id/authorize", controller.AuthorizeProjectHandler)
+	auth.DELETE("/project/:project_id/authorize/:user_id", controller.DeleteAuthorizeProjectHandler)
+	auth.GET("/project/:project_id/version", controller.ListProjectVersionHandler)
+	auth.GET("/project/:project_id/environment", controller.ListEnvironmentHandler)
+	auth.POST("/project/:project_id/environment", controller.CreateEnvironmentHandler)
+	auth.DELETE("/project/:project_id/environment/:name", controller.DeleteEnvironmentHandler)
+	auth.GET("/project/:project_id/schema", controller.ListProjectSchemaHandler)
+	auth.PUT("/project/:project_id/schema", controller.SetProjectSchemaHandler)
+	auth.DELETE("/project/:project_id/schema", controller.DeleteProjectSchemaHandler)
+
+	auth.PUT("/item", controller.CreateItemHandler)
+	auth.POST("/item/:item_id", controller.UpdateItemHandler)
+	auth.DELETE("/item/:item_id", controller.DeleteItemHandler)
+	auth.GET("/item/:item_id", controller.SingleItemHandler)
+	auth.GET("/item/:item_id/version", controller.ListItemVersionHandler)
+	auth.GET("/item/:item_id/schema", controller.ListItemSchemaHandler)
+	auth.PUT("/item/:item_id/schema", controller.SetItemSchemaHandler)
+	auth.DELETE("/item/:item_id/schema", controller.DeleteItemSchemaHandler)
+	auth.GET("/item/:item_id/authorize", controller.ListAuthorizeItemHandler)
+	auth.POST("/item/:item_id/authorize", controller.AuthorizeItemHandler)
+	auth.DELETE("/item/:item_id/authorize/:user_id", controller.DeleteAuthorizeItemHandler)
+	auth.POST("/item/:item_id/rollback", controller.RollbackItemHandler)
+	auth.PUT("/item/:item_id/draft", controller.SaveDraftHandler)
+	auth.DELETE("/item/:item_id/draft", controller.DiscardDraftHandler)
+	auth.POST("/item/:item_id/publish", controller.PublishItemHandler)
+	auth.POST("/item/:item_id/gray", controller.StartGrayHandler)
+	auth.PUT("/item/:item_id/gray", controller.WidenGrayHandler)
+	auth.POST("/item/:item_id/gray/promote", controller.PromoteGrayHandler)
+	auth.DELETE("/item/:item_id/gray", controller.AbortGrayHandler)
+
+	auth.GET("/version/:version_id", controller.SingleVersionHandler)
+	auth.GET("/version/:version_id/diff", controller.DiffVersionHandler)
+
+	auth.GET("/audit", controller.ListAuditHandler)
+
+	auth.POST("/admin/key-rotation", controller.StartKeyRotationHandler)
+	auth.GET("/admin/key-rotation", controller.KeyRotationHandler)
+
+	puller := v1.Group("", middleware.OptionalAuthenticate())
+	puller.GET("/puller/:org/:project/:item", controller.PullHandler)
+	puller.POST("/watch", controller.WatchHandler)
 }
 
 func guldanAccessLogger() gin.HandlerFunc {
@@ -61,7 +127,7 @@ func guldanAccessLogger() gin.HandlerFunc {
 		end := time.Now()
 		latency := end.Sub(start)
 
-		clientIP := c.ClientIP()
+		clientIP := middleware.ClientIp(c)
 		method := c.Request.Method
 		statusCode := c.Writer.Status()
 		log.Infof("[GIN] \"%s %s\" %d %v %vus", method, path, statusCode, clientIP, latency.Nanoseconds()/1000.0)
@@ -82,6 +148,12 @@ func main() {
 	}
 	if *help {
 		flag.PrintDefaults()
+		fmt.Println("subcommands:")
+		fmt.Println("  migrate status          show applied and pending schema migrations")
+		fmt.Println("  migrate up [version]    apply migrations up to version (default: latest)")
+		fmt.Println("  migrate down [version]  roll back migrations down to version (default: one step)")
+		fmt.Println("  rotate-key <from> [to] [batch size]")
+		fmt.Println("                          re-encrypt encrypted items from key <from> to key [to] (default: secret.keyid)")
 		os.Exit(0)
 	}
 	if err := config.LoadConfig(*configFile); err != nil {
@@ -98,11 +170,42 @@ func main() {
 		_ = log.ReplaceLogger(logger)
 	}
 
-	if err := db.InitMysql(config.C); err != nil {
+	if err := repo.Open(config.C); err != nil {
 		_ = log.Criticalf("new middleware fail: %v", err)
 		os.Exit(1)
 	}
-	defer db.Destroy()
+	defer repo.Close()
+	if err := keyring.InitKeyring(config.C); err != nil {
+		_ = log.Criticalf("load secret keys fail: %v", err)
+		os.Exit(1)
+	}
+	if flag.Arg(0) == "migrate" {
+		err := runMigrate(flag.Args()[1:])
+		repo.Close()
+		if err != nil {
+			fmt.Printf("migrate fail: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if flag.Arg(0) == "rotate-key" {
+		err := runRotateKey(flag.Args()[1:])
+		repo.Close()
+		if err != nil {
+			fmt.Printf("rotate-key fail: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if err := checkSchema(); err != nil {
+		_ = log.Criticalf("migrate fail: %v", err)
+		os.Exit(1)
+	}
+	cache.InitCache(config.C)
+	if err := middleware.InitTrustedProxies(config.C); err != nil {
+		_ = log.Criticalf("load trusted proxies fail: %v", err)
+		os.Exit(1)
+	}
 
 	if config.C.Debug {
 		gin.SetMode(gin.DebugMode)
@@ -123,3 +226,102 @@ func main() {
 		_ = log.Errorf("http listen fail: %v", err)
 	}
 }
+
+// automigrate开启时启动前应用全部未执行的迁移, 否则只提示结构版本落后
+func checkSchema() error {
+	migrator, err := repo.GetMigrator()
+	if err == repo.ErrMigrateNotSupported {
+		return nil
+	}
+	latest := repo.LatestVersion(migrator)
+	if config.C.Database.AutoMigrate {
+		return migrator.MigrateTo(latest, func(m repo.Migration, up bool) {
+			log.Infof("schema migrated up to %d_%s", m.Version, m.Name)
+		})
+	}
+	current, err := migrator.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current < latest {
+		_ = log.Warnf("schema version %d is behind %d, run \"migrate up\" first", current, latest)
+	}
+	return nil
+}
+
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate status|up [version]|down [version]")
+	}
+	migrator, err := repo.GetMigrator()
+	if err != nil {
+		return err
+	}
+	current, err := migrator.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	target := -1
+	if len(args) > 1 {
+		if target, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("invalid version: %v", args[1])
+		}
+	}
+	progress := func(m repo.Migration, up bool) {
+		if up {
+			fmt.Printf("up   %d_%s\n", m.Version, m.Name)
+		} else {
+			fmt.Printf("down %d_%s\n", m.Version, m.Name)
+		}
+	}
+	switch args[0] {
+	case "status":
+		fmt.Printf("current version: %d\n", current)
+		for _, m := range migrator.Migrations() {
+			state := "pending"
+			if m.Version <= current {
+				state = "applied"
+			}
+			fmt.Printf("%-8s %d_%s\n", state, m.Version, m.Name)
+		}
+		return nil
+	case "up":
+		if target < 0 {
+			target = repo.LatestVersion(migrator)
+		}
+		if target < current {
+			return fmt.Errorf("version %d is lower than current version %d", target, current)
+		}
+		return migrator.MigrateTo(target, progress)
+	case "down":
+		if target < 0 {
+			target = current - 1
+		}
+		if target < 0 || target > current {
+			return fmt.Errorf("version %d is not lower than current version %d", target, current)
+		}
+		return migrator.MigrateTo(target, progress)
+	}
+	return fmt.Errorf("unknown migrate command: %v", args[0])
+}
+
+// 可以在服务运行期间执行, 中断后重新执行即可从未完成的部分继续
+func runRotateKey(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: rotate-key <from> [to] [batch size]")
+	}
+	to := keyring.Keys.KeyId()
+	if len(args) > 1 {
+		to = args[1]
+	}
+	batchSize := 0
+	if len(args) > 2 {
+		var err error
+		if batchSize, err = strconv.Atoi(args[2]); err != nil {
+			return fmt.Errorf("invalid batch size: %v", args[2])
+		}
+	}
+	return service.RotateKey(args[0], to, batchSize, func(p service.RotateProgress) {
+		fmt.Printf("%-8s %d/%d scanned, %d rotated\n", p.Stage, p.Scanned, p.Total, p.Rotated)
+	})
+}
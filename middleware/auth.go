@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"github.com/cihub/seelog"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"zoe/basic"
+	"zoe/model"
+	"zoe/service"
+)
+
+const (
+	SessionCookieName = "guldan_session"
+	userKey           = "guldan_user"
+)
+
+func GetSessionToken(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	token, _ := c.Cookie(SessionCookieName)
+	return token
+}
+
+// 不便设置Authorization的客户端可以通过X-Guldan-Token或token参数传递API token
+func getApiToken(c *gin.Context) string {
+	if token := c.GetHeader("X-Guldan-Token"); token != "" {
+		return token
+	}
+	return c.Query("token")
+}
+
+func isApiToken(token string) bool {
+	return strings.HasPrefix(token, basic.API_TOKEN_PREFIX)
+}
+
+// 依次尝试会话token(Bearer或cookie)和API token, 以API token前缀开头的凭证按API token认证.
+// user_hash不会过期也无法吊销, 不再作为凭证; 没有携带任何凭证时返回nil, nil
+func authenticate(c *gin.Context) (*model.User, error) {
+	if token := GetSessionToken(c); token != "" {
+		if isApiToken(token) {
+			return service.AuthenticateByToken(token)
+		}
+		return service.AuthenticateBySession(token)
+	}
+	if token := getApiToken(c); token != "" {
+		if !isApiToken(token) {
+			return nil, service.ErrUnauthorized
+		}
+		return service.AuthenticateByToken(token)
+	}
+	return nil, nil
+}
+
+func abortUnauthorized(c *gin.Context, err error) {
+	if err != service.ErrUnauthorized {
+		_ = seelog.Critical(err.Error())
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": -1, "msg": service.ErrUnauthorized.Error()})
+}
+
+func Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := authenticate(c)
+		if err != nil {
+			abortUnauthorized(c, err)
+			return
+		}
+		if user == nil {
+			abortUnauthorized(c, service.ErrUnauthorized)
+			return
+		}
+		// 只读token只能调用管理接口中的查询
+		if user.Token != nil && user.Token.ReadOnly == 1 && c.Request.Method != http.MethodGet {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"code": -1, "msg": "只读token不能修改资源"})
+			return
+		}
+		c.Set(userKey, user)
+		c.Next()
+	}
+}
+
+// 允许匿名访问, 但携带了无效凭证时仍然拒绝
+func OptionalAuthenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := authenticate(c)
+		if err != nil {
+			abortUnauthorized(c, err)
+			return
+		}
+		if user != nil {
+			c.Set(userKey, user)
+		}
+		c.Next()
+	}
+}
+
+// 匿名请求返回nil
+func CurrentUser(c *gin.Context) *model.User {
+	if value, ok := c.Get(userKey); ok {
+		return value.(*model.User)
+	}
+	return nil
+}
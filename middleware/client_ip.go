@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net"
+	"strings"
+	"zoe/config"
+	"zoe/utils"
+)
+
+var trustedProxies []*net.IPNet
+
+func InitTrustedProxies(c *config.Config) error {
+	ipNets, err := utils.ParseIpNets(c.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	trustedProxies = ipNets
+	return nil
+}
+
+// 取代gin的ClientIP, 后者无条件采信X-Forwarded-For, 客户端可以借此伪造ip命中灰度
+func ClientIp(c *gin.Context) string {
+	forwardedFor := strings.Join(c.Request.Header.Values("X-Forwarded-For"), ",")
+	return utils.ResolveClientIp(c.Request.RemoteAddr, forwardedFor, trustedProxies)
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// 组织配置树的导出格式. 名称都不带上级前缀, 以便导入到其他名称的组织下
+type OrgArchive struct {
+	FormatVersion int                `json:"format_version" yaml:"format_version"`
+	ExportedAt    time.Time          `json:"exported_at" yaml:"exported_at"`
+	Name          string             `json:"name" yaml:"name"`
+	Private       bool               `json:"private" yaml:"private"`
+	Privileges    []ArchivePrivilege `json:"privileges,omitempty" yaml:"privileges,omitempty"`
+	Projects      []ArchiveProject   `json:"projects" yaml:"projects"`
+}
+
+type ArchiveProject struct {
+	Name         string             `json:"name" yaml:"name"`
+	Private      bool               `json:"private" yaml:"private"`
+	Environments []string           `json:"environments,omitempty" yaml:"environments,omitempty"`
+	Schema       string             `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Privileges   []ArchivePrivilege `json:"privileges,omitempty" yaml:"privileges,omitempty"`
+	Items        []ArchiveItem      `json:"items" yaml:"items"`
+}
+
+// 同名item在不同环境下各导出一条, Environment为空表示默认环境.
+// 加密item导出明文, 导入时用目标实例的密钥重新加密
+type ArchiveItem struct {
+	Name        string             `json:"name" yaml:"name"`
+	Environment string             `json:"environment,omitempty" yaml:"environment,omitempty"`
+	Private     bool               `json:"private" yaml:"private"`
+	ContentType string             `json:"content_type,omitempty" yaml:"content_type,omitempty"`
+	Content     string             `json:"content" yaml:"content"`
+	Encrypted   bool               `json:"encrypted,omitempty" yaml:"encrypted,omitempty"`
+	Schema      string             `json:"schema,omitempty" yaml:"schema,omitempty"`
+	VersionId   int                `json:"version_id" yaml:"version_id"`
+	Privileges  []ArchivePrivilege `json:"privileges,omitempty" yaml:"privileges,omitempty"`
+}
+
+// 授权按用户名导出, 导入时按用户名匹配目标实例中的用户
+type ArchivePrivilege struct {
+	User        string `json:"user" yaml:"user"`
+	Type        string `json:"type" yaml:"type"`
+	Environment string `json:"environment,omitempty" yaml:"environment,omitempty"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type Audit struct {
+	Id           int       `db:"id"`
+	OrgId        int       `db:"org_id"`
+	UserId       int       `db:"user_id"`
+	Action       string    `db:"action"`
+	ResourceType int       `db:"resource_type"`
+	ResourceId   int       `db:"resource_id"`
+	ResourceName string    `db:"resource_name"`
+	TargetUserId int       `db:"target_user_id"`
+	Before       string    `db:"before_value"`
+	After        string    `db:"after_value"`
+	CreateAt     time.Time `db:"created_at"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+type Draft struct {
+	Id        int       `db:"id"`
+	ItemId    int       `db:"item_id"`
+	Content   string    `db:"content"`
+	UserId    int       `db:"user_id"`
+	IsDeleted int       `db:"is_deleted"`
+	UpdatedAt time.Time `db:"updated_at"`
+	CreateAt  time.Time `db:"created_at"`
+}
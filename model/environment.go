@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type Environment struct {
+	Id        int       `db:"id"`
+	ProjectId int       `db:"project_id"`
+	Name      string    `db:"name"`
+	IsDeleted int       `db:"is_deleted"`
+	UpdatedAt time.Time `db:"updated_at"`
+	CreateAt  time.Time `db:"created_at"`
+}
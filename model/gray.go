@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type GrayRelease struct {
+	Id         int       `db:"id"`
+	ItemId     int       `db:"item_id"`
+	VersionId  int       `db:"version_id"`
+	ClientIds  string    `db:"client_ids"`
+	IpRanges   string    `db:"ip_ranges"`
+	Percentage int       `db:"percentage"`
+	Status     int       `db:"status"`
+	UserId     int       `db:"user_id"`
+	IsDeleted  int       `db:"is_deleted"`
+	UpdatedAt  time.Time `db:"updated_at"`
+	CreateAt   time.Time `db:"created_at"`
+}
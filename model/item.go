@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type Item struct {
+	Id               int       `db:"id"`
+	Name             string    `db:"name"`
+	ParentId         int       `db:"parent_id"`
+	Visibility       int       `db:"visibility"`
+	Content          string    `db:"content"`
+	CurrentVersionId int       `db:"current_version_id"`
+	IsDeleted        int       `db:"is_deleted"`
+	UpdatedAt        time.Time `db:"updated_at"`
+	CreateAt         time.Time `db:"created_at"`
+	// 所属环境, 空串为默认环境
+	Environment string `db:"environment"`
+	// 内容格式, 保存和发布时按格式校验
+	ContentType string `db:"content_type"`
+	// 为1时内容及其版本、草稿都以密文保存
+	Encrypted int `db:"encrypted"`
+}
@@ -14,4 +14,6 @@ type Privilege struct {
 	IsDeleted          int       `db:"is_deleted"`
 	UpdatedAt          time.Time `db:"updated_at"`
 	CreateAt           time.Time `db:"created_at"`
+	// 非空时授权只对该环境下的item生效
+	Environment string `db:"environment"`
 }
@@ -1,5 +1,25 @@
 package model
 
+import "encoding/json"
+
+type RegisterRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type LoginRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type CreateTokenRequest struct {
+	Name         string `json:"name" binding:"required"`
+	ReadOnly     bool   `json:"read_only"`
+	ResourceType string `json:"resource_type"`
+	ResourceId   int    `json:"resource_id"`
+	ExpireDays   int    `json:"expire_days"`
+}
+
 type OrgCreateRequest struct {
 	Name    string `json:"name" binding:"required"`
 	Private bool   `json:"private"`
@@ -10,6 +30,18 @@ type OrgUpdateRequest struct {
 }
 
 type AuthorizeOrgRequest struct {
+	Type        string `json:"type"`
+	UserId      int    `json:"user_id"`
+	Environment string `json:"environment"`
+}
+
+type AuthorizeProjectRequest struct {
+	Type        string `json:"type"`
+	UserId      int    `json:"user_id"`
+	Environment string `json:"environment"`
+}
+
+type AuthorizeItemRequest struct {
 	Type   string `json:"type"`
 	UserId int    `json:"user_id"`
 }
@@ -23,3 +55,105 @@ type CreateProjectRequest struct {
 type UpdateProjectRequest struct {
 	Private string `json:"private"`
 }
+
+type CreateItemRequest struct {
+	ParentId int    `json:"parent_id" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Content  string `json:"content"`
+	Private  string `json:"private"`
+	// 为空时创建在默认环境下
+	Environment string `json:"environment"`
+	// 为空时按纯文本处理
+	ContentType string `json:"content_type"`
+	// 内容加密保存, 只对拉取方和有修改权限的用户解密
+	Encrypted bool `json:"encrypted"`
+}
+
+type UpdateItemRequest struct {
+	Content     *string `json:"content"`
+	Private     *string `json:"private"`
+	ContentType *string `json:"content_type"`
+	Encrypted   *bool   `json:"encrypted"`
+}
+
+type SaveDraftRequest struct {
+	Content string `json:"content"`
+}
+
+type StartGrayRequest struct {
+	Content    string   `json:"content"`
+	ClientIds  []string `json:"client_ids"`
+	IpRanges   []string `json:"ip_ranges"`
+	Percentage int      `json:"percentage"`
+}
+
+type UpdateGrayRequest struct {
+	ClientIds  []string `json:"client_ids"`
+	IpRanges   []string `json:"ip_ranges"`
+	Percentage int      `json:"percentage"`
+}
+
+type WatchItem struct {
+	Name        string `json:"name" binding:"required"`
+	VersionId   int    `json:"version_id"`
+	Environment string `json:"environment"`
+}
+
+type WatchRequest struct {
+	Items   []WatchItem `json:"items" binding:"required"`
+	Timeout int         `json:"timeout"`
+}
+
+type RollbackItemRequest struct {
+	VersionId int `json:"version_id" binding:"required"`
+}
+
+// 审计日志查询条件, since/until为unix时间戳(秒)
+type AuditQuery struct {
+	OrgId        int    `form:"org_id" binding:"required"`
+	UserId       int    `form:"user_id"`
+	TargetUserId int    `form:"target_user_id"`
+	Action       string `form:"action"`
+	ResourceType string `form:"resource_type"`
+	ResourceId   int    `form:"resource_id"`
+	Since        int64  `form:"since"`
+	Until        int64  `form:"until"`
+	Page         int    `form:"page"`
+	PageSize     int    `form:"page_size"`
+}
+
+// 组织、项目和授权列表的查询条件, q按名称搜索, sort为排序字段, 加前缀-表示倒序
+type ListQuery struct {
+	Q        string `form:"q"`
+	Sort     string `form:"sort"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
+
+type ExportOrgQuery struct {
+	Format     string `form:"format"`
+	Privileges bool   `form:"privileges"`
+}
+
+type ImportOrgQuery struct {
+	Name       string `form:"name"`
+	Conflict   string `form:"conflict"`
+	Format     string `form:"format"`
+	Privileges bool   `form:"privileges"`
+}
+
+type CreateEnvironmentRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// schema直接以JSON对象提交
+type SetSchemaRequest struct {
+	Schema json.RawMessage `json:"schema" binding:"required"`
+}
+
+// 把密文从From密钥重新加密为To密钥, To为空时使用配置中当前的密钥
+type RotateKeyRequest struct {
+	From      string `json:"from" binding:"required"`
+	To        string `json:"to"`
+	BatchSize int    `json:"batch_size"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// project或item上的JSON Schema, 每次修改追加一个新版本, 内容为空表示已移除
+type Schema struct {
+	Id           int       `db:"id"`
+	ResourceId   int       `db:"resource_id"`
+	ResourceType int       `db:"resource_type"`
+	Version      int       `db:"version"`
+	Content      string    `db:"content"`
+	UserId       int       `db:"user_id"`
+	CreateAt     time.Time `db:"created_at"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+type Session struct {
+	Id        int       `db:"id"`
+	UserId    int       `db:"user_id"`
+	TokenHash string    `db:"token_hash"`
+	ExpiredAt time.Time `db:"expired_at"`
+	IsDeleted int       `db:"is_deleted"`
+	UpdatedAt time.Time `db:"updated_at"`
+	CreateAt  time.Time `db:"created_at"`
+}
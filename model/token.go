@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type Token struct {
+	Id           int        `db:"id"`
+	UserId       int        `db:"user_id"`
+	Name         string     `db:"name"`
+	TokenHash    string     `db:"token_hash"`
+	ReadOnly     int        `db:"read_only"`
+	ResourceType int        `db:"resource_type"`
+	ResourceId   int        `db:"resource_id"`
+	ExpiredAt    time.Time  `db:"expired_at"`
+	LastUsedAt   *time.Time `db:"last_used_at"`
+	IsDeleted    int        `db:"is_deleted"`
+	UpdatedAt    time.Time  `db:"updated_at"`
+	CreateAt     time.Time  `db:"created_at"`
+}
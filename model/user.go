@@ -6,8 +6,10 @@ type User struct {
 	Id         int       `db:"id"`
 	Name       string    `db:"name"`
 	UserHash   string    `db:"user_hash"`
-	SecretHash int       `db:"secret_hash"`
+	SecretHash string    `db:"secret_hash"`
 	IsDeleted  int       `db:"is_deleted"`
 	UpdatedAt  time.Time `db:"updated_at"`
 	CreateAt   time.Time `db:"created_at"`
+	// 通过API token认证时为该token, 用于限制只读和资源范围
+	Token *Token `db:"-"`
 }
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type Version struct {
+	Id           int       `db:"id"`
+	ResourceId   int       `db:"resource_id"`
+	ResourceType int       `db:"resource_type"`
+	ResourceName string    `db:"resource_name"`
+	Visibility   int       `db:"visibility"`
+	Content      string    `db:"content"`
+	UserId       int       `db:"user_id"`
+	RollbackFrom int       `db:"rollback_from"`
+	CreateAt     time.Time `db:"created_at"`
+	Encrypted    int       `db:"encrypted"`
+}
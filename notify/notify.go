@@ -0,0 +1,49 @@
+package notify
+
+import "sync"
+
+type Hub struct {
+	mu       sync.Mutex
+	watchers map[string]map[chan string]struct{}
+}
+
+var ItemHub = NewHub()
+
+func NewHub() *Hub {
+	return &Hub{watchers: make(map[string]map[chan string]struct{})}
+}
+
+// 订阅一组资源的变更通知, 返回的cancel必须在结束等待后调用
+func (h *Hub) Subscribe(resNames []string) (<-chan string, func()) {
+	ch := make(chan string, 1)
+	h.mu.Lock()
+	for _, name := range resNames {
+		if h.watchers[name] == nil {
+			h.watchers[name] = make(map[chan string]struct{})
+		}
+		h.watchers[name][ch] = struct{}{}
+	}
+	h.mu.Unlock()
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for _, name := range resNames {
+			delete(h.watchers[name], ch)
+			if len(h.watchers[name]) == 0 {
+				delete(h.watchers, name)
+			}
+		}
+	}
+	return ch, cancel
+}
+
+func (h *Hub) Publish(resName string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.watchers[resName] {
+		select {
+		case ch <- resName:
+		default:
+		}
+	}
+}
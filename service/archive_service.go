@@ -0,0 +1,721 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+	"zoe/basic"
+	"zoe/cache"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/notify"
+	"zoe/utils"
+)
+
+func listArchivePrivileges(tx repo.Tx, resId, resType int, userNames map[int]string) ([]model.ArchivePrivilege, error) {
+	privileges, err := tx.Privileges().ListByResource(resId, resType)
+	if err != nil {
+		return nil, err
+	}
+	var userIds []int
+	for _, privilege := range *privileges {
+		if _, ok := userNames[privilege.UserId]; !ok {
+			userIds = append(userIds, privilege.UserId)
+		}
+	}
+	if len(userIds) > 0 {
+		users, err := tx.Users().ListByIds(userIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range *users {
+			userNames[user.Id] = user.Name
+		}
+	}
+	var result []model.ArchivePrivilege
+	for _, privilege := range *privileges {
+		name, ok := userNames[privilege.UserId]
+		if !ok {
+			continue
+		}
+		result = append(result, model.ArchivePrivilege{
+			User:        name,
+			Type:        utils.FormatPrivilegeType(privilege.PrivilegeType),
+			Environment: privilege.Environment,
+		})
+	}
+	return result, nil
+}
+
+func getArchiveSchema(tx repo.Tx, resId, resType int) (string, error) {
+	schema, err := tx.Schemas().GetLatest(resId, resType)
+	if err != nil || schema == nil {
+		return "", err
+	}
+	return schema.Content, nil
+}
+
+// 导出组织下所有project和item的当前版本, 需要组织的修改权限
+func ExportOrg(user *model.User, orgId int, withPrivileges bool) (*model.OrgArchive, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	org, err := tx.Orgs().GetById(orgId)
+	if org == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("不存在的组织")
+	}
+	flag, err := repo.ValidateForUserModifyOrg(tx, user, orgId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限导出该组织")
+	}
+	archive := &model.OrgArchive{
+		FormatVersion: basic.ARCHIVE_FORMAT_VERSION,
+		ExportedAt:    time.Now(),
+		Name:          org.Name,
+		Private:       org.Visibility == 0,
+		Projects:      []model.ArchiveProject{},
+	}
+	userNames := make(map[int]string)
+	if withPrivileges {
+		if archive.Privileges, err = listArchivePrivileges(tx, org.Id, basic.Resource_Type_ORG, userNames); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	projects, err := tx.Projects().ListByParentId(org.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	for _, project := range *projects {
+		archiveProject := model.ArchiveProject{
+			Name:    strings.TrimPrefix(project.Name, org.Name+"."),
+			Private: project.Visibility == 0,
+			Items:   []model.ArchiveItem{},
+		}
+		envs, err := tx.Environments().ListByProjectId(project.Id)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		for _, env := range *envs {
+			archiveProject.Environments = append(archiveProject.Environments, env.Name)
+		}
+		if archiveProject.Schema, err = getArchiveSchema(tx, project.Id, basic.Resource_Type_PROJECT); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if withPrivileges {
+			archiveProject.Privileges, err = listArchivePrivileges(tx, project.Id, basic.Resource_Type_PROJECT, userNames)
+			if err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+		}
+		items, err := tx.Items().ListByParentId(project.Id)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		for _, item := range *items {
+			content, err := openContent(&item, item.Content)
+			if err != nil {
+				_ = tx.Rollback()
+				return nil, fmt.Errorf("%s: %s", utils.GetItemKey(item.Name, item.Environment), err.Error())
+			}
+			archiveItem := model.ArchiveItem{
+				Name:        strings.TrimPrefix(item.Name, project.Name+"."),
+				Environment: item.Environment,
+				Private:     item.Visibility == 0,
+				ContentType: item.ContentType,
+				Content:     content,
+				Encrypted:   item.Encrypted == 1,
+				VersionId:   item.CurrentVersionId,
+			}
+			if archiveItem.Schema, err = getArchiveSchema(tx, item.Id, basic.Resource_Type_ITEM); err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+			if withPrivileges {
+				archiveItem.Privileges, err = listArchivePrivileges(tx, item.Id, basic.Resource_Type_ITEM, userNames)
+				if err != nil {
+					_ = tx.Rollback()
+					return nil, err
+				}
+			}
+			archiveProject.Items = append(archiveProject.Items, archiveItem)
+		}
+		archive.Projects = append(archive.Projects, archiveProject)
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return archive, nil
+}
+
+func validateArchive(archive *model.OrgArchive, orgName string) error {
+	if archive.FormatVersion != basic.ARCHIVE_FORMAT_VERSION {
+		return fmt.Errorf("不支持的导出文件版本: %d", archive.FormatVersion)
+	}
+	if orgName == "" || strings.Contains(orgName, ".") || len(orgName) >= basic.MAX_RESOURCE_NAME_LENGTH {
+		return fmt.Errorf("非法的组织名: %s", orgName)
+	}
+	for _, privilege := range archive.Privileges {
+		if privilege.Environment != "" {
+			if err := validateEnvironmentName(privilege.Environment); err != nil {
+				return fmt.Errorf("%s: %s", err.Error(), privilege.Environment)
+			}
+		}
+	}
+	projectNames := make(map[string]bool)
+	for _, project := range archive.Projects {
+		name := orgName + "." + project.Name
+		if project.Name == "" || strings.Contains(project.Name, ".") || len(name) >= basic.MAX_RESOURCE_NAME_LENGTH {
+			return fmt.Errorf("非法的project名: %s", project.Name)
+		}
+		if projectNames[project.Name] {
+			return fmt.Errorf("重复的project: %s", project.Name)
+		}
+		projectNames[project.Name] = true
+		if project.Schema != "" {
+			if err := utils.CompileSchema(project.Schema); err != nil {
+				return fmt.Errorf("%s: %s", project.Name, err.Error())
+			}
+		}
+		envNames := map[string]bool{"": true}
+		for _, env := range project.Environments {
+			if err := validateEnvironmentName(env); err != nil {
+				return fmt.Errorf("%s: %s.%s", err.Error(), project.Name, env)
+			}
+			envNames[env] = true
+		}
+		for _, privilege := range project.Privileges {
+			if !envNames[privilege.Environment] {
+				return fmt.Errorf("授权引用了未定义的环境: %s@%s", project.Name, privilege.Environment)
+			}
+		}
+		itemNames := make(map[string]bool)
+		for _, item := range project.Items {
+			if item.Name == "" || strings.Contains(item.Name, ".") || len(name+"."+item.Name) >= basic.MAX_RESOURCE_NAME_LENGTH {
+				return fmt.Errorf("非法的item名: %s.%s", project.Name, item.Name)
+			}
+			if !envNames[item.Environment] {
+				return fmt.Errorf("item引用了未定义的环境: %s.%s@%s", project.Name, item.Name, item.Environment)
+			}
+			key := utils.GetItemKey(item.Name, item.Environment)
+			if itemNames[key] {
+				return fmt.Errorf("重复的item: %s.%s", project.Name, key)
+			}
+			itemNames[key] = true
+			contentType, err := utils.ParseContentType(item.ContentType)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %s", project.Name, key, err.Error())
+			}
+			if err = utils.ValidateContent(contentType, item.Content); err != nil {
+				return fmt.Errorf("%s.%s: %s", project.Name, key, err.Error())
+			}
+			if item.Schema != "" {
+				if contentType == basic.Content_Type_TEXT {
+					return fmt.Errorf("%s.%s: 纯文本格式的item不能设置schema", project.Name, key)
+				}
+				if err = utils.CompileSchema(item.Schema); err != nil {
+					return fmt.Errorf("%s.%s: %s", project.Name, key, err.Error())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func parseVisibilityFlag(private bool) int {
+	if private {
+		return 0
+	}
+	return 1
+}
+
+// 一次导入在同一个事务中完成, conflict为fail时遇到冲突整体回滚.
+// 已存在且内容、可见性都相同的资源不算冲突; project和org只比较可见性, 冲突时也会继续导入其下的item
+type orgImporter struct {
+	tx             repo.Tx
+	user           *model.User
+	conflict       string
+	withPrivileges bool
+	users          map[string]*model.User
+
+	created      []string
+	updated      []string
+	skipped      []string
+	unchanged    []string
+	skippedUsers []string
+	published    []string
+}
+
+func (im *orgImporter) overwrite(name string) (bool, error) {
+	switch im.conflict {
+	case basic.Import_Conflict_OVERWRITE:
+		im.updated = append(im.updated, name)
+		return true, nil
+	case basic.Import_Conflict_SKIP:
+		im.skipped = append(im.skipped, name)
+		return false, nil
+	}
+	return false, fmt.Errorf("%s已经存在且与导入内容不同", name)
+}
+
+func (im *orgImporter) importOrg(archive *model.OrgArchive, orgName string) (*model.Org, error) {
+	visibility := parseVisibilityFlag(archive.Private)
+	org, err := im.tx.Orgs().GetByName(orgName)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		if im.user.Token != nil && im.user.Token.ResourceType != 0 {
+			return nil, errors.New("限定范围的token不能创建组织")
+		}
+		id, err := im.tx.Orgs().Create(orgName, visibility)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = createVersion(im.tx, im.user.Id, id, basic.Resource_Type_ORG, orgName, "", visibility, 0); err != nil {
+			return nil, err
+		}
+		err = repo.AddWithCheck(im.tx, im.user.UserHash, orgName, id,
+			basic.Resource_Type_ORG, im.user.Id, basic.Privilege_Type_MODIFIER, visibility)
+		if err != nil {
+			return nil, err
+		}
+		org = &model.Org{Id: id, Name: orgName, Visibility: visibility}
+		err = recordAudit(im.tx, im.user, &model.Audit{
+			OrgId:        id,
+			Action:       basic.Audit_Action_CREATE,
+			ResourceType: basic.Resource_Type_ORG,
+			ResourceId:   id,
+			ResourceName: orgName,
+		}, nil, orgSnapshot(org))
+		if err != nil {
+			return nil, err
+		}
+		im.created = append(im.created, orgName)
+		return org, nil
+	}
+	flag, err := repo.ValidateForUserModifyOrg(im.tx, im.user, org.Id)
+	if err != nil || !flag {
+		return nil, errors.New("用户无权限修改该组织")
+	}
+	if org.Visibility == visibility {
+		im.unchanged = append(im.unchanged, orgName)
+		return org, nil
+	}
+	flag, err = im.overwrite(orgName)
+	if err != nil || !flag {
+		return org, err
+	}
+	if err = im.tx.Orgs().UpdateVisibility(org.Id, visibility); err != nil {
+		return nil, err
+	}
+	if _, err = createVersion(im.tx, im.user.Id, org.Id, basic.Resource_Type_ORG, org.Name, "", visibility, 0); err != nil {
+		return nil, err
+	}
+	after := *org
+	after.Visibility = visibility
+	err = recordAudit(im.tx, im.user, &model.Audit{
+		OrgId:        org.Id,
+		Action:       basic.Audit_Action_UPDATE,
+		ResourceType: basic.Resource_Type_ORG,
+		ResourceId:   org.Id,
+		ResourceName: org.Name,
+	}, orgSnapshot(org), orgSnapshot(&after))
+	if err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
+
+func (im *orgImporter) importProject(org *model.Org, archiveProject *model.ArchiveProject) (*model.Project, error) {
+	name := org.Name + "." + archiveProject.Name
+	visibility := parseVisibilityFlag(archiveProject.Private)
+	project, err := im.tx.Projects().GetByParentIdAndName(org.Id, name)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		id, err := im.tx.Projects().Create(name, visibility, org.Id)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = createVersion(im.tx, im.user.Id, id, basic.Resource_Type_PROJECT, name, "", visibility, 0); err != nil {
+			return nil, err
+		}
+		err = repo.AddWithCheck(im.tx, im.user.UserHash, name, id,
+			basic.Resource_Type_PROJECT, im.user.Id, basic.Privilege_Type_MODIFIER, visibility)
+		if err != nil {
+			return nil, err
+		}
+		project = &model.Project{Id: id, Name: name, ParentId: org.Id, Visibility: visibility}
+		err = recordProjectAudit(im.tx, im.user, basic.Audit_Action_CREATE, project, 0, nil, projectSnapshot(project))
+		if err != nil {
+			return nil, err
+		}
+		im.created = append(im.created, name)
+		return project, nil
+	}
+	if project.Visibility == visibility {
+		im.unchanged = append(im.unchanged, name)
+		return project, nil
+	}
+	flag, err := im.overwrite(name)
+	if err != nil || !flag {
+		return project, err
+	}
+	if err = im.tx.Projects().UpdateVisibility(project.Id, visibility); err != nil {
+		return nil, err
+	}
+	if _, err = createVersion(im.tx, im.user.Id, project.Id, basic.Resource_Type_PROJECT, name, "", visibility, 0); err != nil {
+		return nil, err
+	}
+	after := *project
+	after.Visibility = visibility
+	err = recordProjectAudit(im.tx, im.user, basic.Audit_Action_UPDATE, project, 0, projectSnapshot(project), projectSnapshot(&after))
+	if err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
+
+// schema按独立的资源处理, 在列表中记为"资源名#schema"; 导入文件中没有schema时保留已有的
+func (im *orgImporter) importSchema(name string, project *model.Project, item *model.Item, schema string) error {
+	content, err := normalizeSchema(schema)
+	if err != nil || content == "" {
+		return err
+	}
+	resId, resType := 0, basic.Resource_Type_PROJECT
+	if project != nil {
+		resId = project.Id
+	} else {
+		resId, resType = item.Id, basic.Resource_Type_ITEM
+	}
+	label := name + "#schema"
+	current, err := im.tx.Schemas().GetLatest(resId, resType)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Content == content {
+		im.unchanged = append(im.unchanged, label)
+		return nil
+	}
+	if current != nil && current.Content != "" {
+		flag, err := im.overwrite(label)
+		if err != nil || !flag {
+			return err
+		}
+	} else {
+		im.created = append(im.created, label)
+	}
+	before, after, _, err := setSchema(im.tx, im.user.Id, resId, resType, content)
+	if err != nil {
+		return err
+	}
+	if project != nil {
+		return recordProjectAudit(im.tx, im.user, basic.Audit_Action_SET_SCHEMA, project, 0, schemaSnapshot(before), schemaSnapshot(after))
+	}
+	return recordItemAudit(im.tx, im.user, basic.Audit_Action_SET_SCHEMA, item, 0, schemaSnapshot(before), schemaSnapshot(after))
+}
+
+// 环境只有名称, 已存在即视为相同
+func (im *orgImporter) importEnvironments(project *model.Project, envs []string) error {
+	for _, name := range envs {
+		env, err := im.tx.Environments().GetByProjectIdAndName(project.Id, name)
+		if err != nil {
+			return err
+		}
+		if env != nil {
+			continue
+		}
+		if _, err = im.tx.Environments().Create(project.Id, name); err != nil {
+			return err
+		}
+		err = recordProjectAudit(im.tx, im.user, basic.Audit_Action_CREATE_ENVIRONMENT, project, 0,
+			nil, gin.H{"environment": name})
+		if err != nil {
+			return err
+		}
+		im.created = append(im.created, utils.GetItemKey(project.Name, name))
+	}
+	return nil
+}
+
+// 返回的bool表示是否发布了新内容, 发布的内容还要经过schema校验
+func (im *orgImporter) importItem(project *model.Project, archiveItem *model.ArchiveItem) (*model.Item, bool, error) {
+	name := project.Name + "." + archiveItem.Name
+	key := utils.GetItemKey(name, archiveItem.Environment)
+	visibility := parseVisibilityFlag(archiveItem.Private)
+	contentType, err := utils.ParseContentType(archiveItem.ContentType)
+	if err != nil {
+		return nil, false, err
+	}
+	encrypted := 0
+	if archiveItem.Encrypted {
+		encrypted = 1
+	}
+	content, err := sealContent(&model.Item{Encrypted: encrypted}, archiveItem.Content)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %s", key, err.Error())
+	}
+	item, err := im.tx.Items().GetByParentIdAndName(project.Id, name, archiveItem.Environment)
+	if err != nil {
+		return nil, false, err
+	}
+	if item == nil {
+		id, err := im.tx.Items().Create(name, content, archiveItem.Environment, contentType, visibility, encrypted, project.Id)
+		if err != nil {
+			return nil, false, err
+		}
+		versionId, err := createVersion(im.tx, im.user.Id, id, basic.Resource_Type_ITEM, name, content, visibility, encrypted)
+		if err != nil {
+			return nil, false, err
+		}
+		err = repo.AddWithCheck(im.tx, im.user.UserHash, name, id,
+			basic.Resource_Type_ITEM, im.user.Id, basic.Privilege_Type_MODIFIER, visibility)
+		if err != nil {
+			return nil, false, err
+		}
+		item = &model.Item{Id: id, Name: name, ParentId: project.Id, Visibility: visibility, Content: content,
+			CurrentVersionId: versionId, Environment: archiveItem.Environment, ContentType: contentType, Encrypted: encrypted}
+		if err = recordItemAudit(im.tx, im.user, basic.Audit_Action_CREATE, item, 0, nil, itemSnapshot(item)); err != nil {
+			return nil, false, err
+		}
+		im.created = append(im.created, key)
+		return item, true, nil
+	}
+	current, err := openContent(item, item.Content)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %s", key, err.Error())
+	}
+	if current == archiveItem.Content && item.Visibility == visibility && item.ContentType == contentType && item.Encrypted == encrypted {
+		im.unchanged = append(im.unchanged, key)
+		return item, false, nil
+	}
+	flag, err := im.overwrite(key)
+	if err != nil || !flag {
+		return item, false, err
+	}
+	gray, err := im.tx.Grays().GetRunningByItemId(item.Id)
+	if err != nil {
+		return nil, false, err
+	}
+	if gray != nil {
+		return nil, false, fmt.Errorf("%s正在灰度发布中, 请先全量或终止灰度", key)
+	}
+	if _, err = setItemEncrypted(im.tx, item, encrypted); err != nil {
+		return nil, false, fmt.Errorf("%s: %s", key, err.Error())
+	}
+	if err = im.tx.Items().UpdateContent(item.Id, content); err != nil {
+		return nil, false, err
+	}
+	if err = im.tx.Items().UpdateVisibility(item.Id, visibility); err != nil {
+		return nil, false, err
+	}
+	if err = im.tx.Items().UpdateContentType(item.Id, contentType); err != nil {
+		return nil, false, err
+	}
+	versionId, err := createVersion(im.tx, im.user.Id, item.Id, basic.Resource_Type_ITEM, name, content, visibility, encrypted)
+	if err != nil {
+		return nil, false, err
+	}
+	after := *item
+	after.Content = content
+	after.Visibility = visibility
+	after.ContentType = contentType
+	after.Encrypted = encrypted
+	after.CurrentVersionId = versionId
+	if err = recordItemAudit(im.tx, im.user, basic.Audit_Action_UPDATE, item, 0, itemSnapshot(item), itemSnapshot(&after)); err != nil {
+		return nil, false, err
+	}
+	im.published = append(im.published, key)
+	return &after, true, nil
+}
+
+func (im *orgImporter) getUser(name string) (*model.User, error) {
+	if user, ok := im.users[name]; ok {
+		return user, nil
+	}
+	user, err := im.tx.Users().GetByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		im.skippedUsers = append(im.skippedUsers, name)
+	}
+	im.users[name] = user
+	return user, nil
+}
+
+// 按用户名匹配授权, 目标实例中不存在的用户跳过; 已有授权只在conflict为overwrite时修改
+func (im *orgImporter) importPrivileges(privileges []model.ArchivePrivilege, orgId, resId, resType int, resName string, resVisibility int) error {
+	if !im.withPrivileges {
+		return nil
+	}
+	for _, archivePrivilege := range privileges {
+		priType, err := utils.ParsePrivilegeType(archivePrivilege.Type)
+		if err != nil {
+			return err
+		}
+		targetUser, err := im.getUser(archivePrivilege.User)
+		if err != nil {
+			return err
+		}
+		if targetUser == nil || targetUser.Id == im.user.Id {
+			continue
+		}
+		environment := archivePrivilege.Environment
+		if resType == basic.Resource_Type_ITEM {
+			environment = ""
+		}
+		privilege, err := im.tx.Privileges().Get(targetUser.UserHash, environment, resId, resType)
+		if err != nil {
+			return err
+		}
+		var before interface{}
+		if privilege == nil {
+			err = im.tx.Privileges().Create(targetUser.UserHash, resName, environment, resId, resType, targetUser.Id, priType, resVisibility)
+		} else if privilege.PrivilegeType != priType && im.conflict == basic.Import_Conflict_OVERWRITE {
+			before = privilegeSnapshot(targetUser, privilege.PrivilegeType, privilege.Environment)
+			err = im.tx.Privileges().Update(targetUser.UserHash, environment, priType, resId, resType)
+		} else {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = recordAudit(im.tx, im.user, &model.Audit{
+			OrgId:        orgId,
+			Action:       basic.Audit_Action_AUTHORIZE,
+			ResourceType: resType,
+			ResourceId:   resId,
+			ResourceName: resName,
+			TargetUserId: targetUser.Id,
+		}, before, privilegeSnapshot(targetUser, priType, environment))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (im *orgImporter) run(archive *model.OrgArchive, orgName string) (*model.Org, error) {
+	org, err := im.importOrg(archive, orgName)
+	if err != nil {
+		return nil, err
+	}
+	err = im.importPrivileges(archive.Privileges, org.Id, org.Id, basic.Resource_Type_ORG, org.Name, org.Visibility)
+	if err != nil {
+		return nil, err
+	}
+	for index := range archive.Projects {
+		archiveProject := &archive.Projects[index]
+		project, err := im.importProject(org, archiveProject)
+		if err != nil {
+			return nil, err
+		}
+		if err = im.importEnvironments(project, archiveProject.Environments); err != nil {
+			return nil, err
+		}
+		if err = im.importSchema(project.Name, project, nil, archiveProject.Schema); err != nil {
+			return nil, err
+		}
+		err = im.importPrivileges(archiveProject.Privileges, org.Id, project.Id, basic.Resource_Type_PROJECT, project.Name, project.Visibility)
+		if err != nil {
+			return nil, err
+		}
+		for index := range archiveProject.Items {
+			archiveItem := &archiveProject.Items[index]
+			item, published, err := im.importItem(project, archiveItem)
+			if err != nil {
+				return nil, err
+			}
+			key := utils.GetItemKey(item.Name, item.Environment)
+			if err = im.importSchema(key, nil, item, archiveItem.Schema); err != nil {
+				return nil, err
+			}
+			if published {
+				if err = checkContentSchema(im.tx, project.Id, item.Id, item.ContentType, archiveItem.Content); err != nil {
+					return nil, fmt.Errorf("%s: %s", key, err.Error())
+				}
+			}
+			err = im.importPrivileges(archiveItem.Privileges, org.Id, item.Id, basic.Resource_Type_ITEM, item.Name, item.Visibility)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return org, nil
+}
+
+// 导入到query.Name指定的组织, 未指定时使用导出时的组织名; 组织不存在时自动创建
+func ImportOrg(user *model.User, query model.ImportOrgQuery, archive *model.OrgArchive) (gin.H, error) {
+	conflict := query.Conflict
+	if conflict == "" {
+		conflict = basic.Import_Conflict_FAIL
+	}
+	if conflict != basic.Import_Conflict_SKIP && conflict != basic.Import_Conflict_OVERWRITE && conflict != basic.Import_Conflict_FAIL {
+		return nil, errors.New("conflict只能是skip、overwrite或fail")
+	}
+	orgName := query.Name
+	if orgName == "" {
+		orgName = archive.Name
+	}
+	if err := validateArchive(archive, orgName); err != nil {
+		return nil, err
+	}
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	im := &orgImporter{
+		tx:             tx,
+		user:           user,
+		conflict:       conflict,
+		withPrivileges: query.Privileges,
+		users:          make(map[string]*model.User),
+		created:        []string{},
+		updated:        []string{},
+		skipped:        []string{},
+		unchanged:      []string{},
+		skippedUsers:   []string{},
+	}
+	org, err := im.run(archive, orgName)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(org.Name)
+	for _, name := range im.published {
+		notify.ItemHub.Publish(name)
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": gin.H{
+			"org_id":        org.Id,
+			"org_name":      org.Name,
+			"created":       im.created,
+			"updated":       im.updated,
+			"skipped":       im.skipped,
+			"unchanged":     im.unchanged,
+			"skipped_users": im.skippedUsers,
+		},
+	}, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"testing"
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/model"
+)
+
+func TestExportOrg(t *testing.T) {
+	f := newFixture(t)
+	f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=prod", Environment: "prod", Encrypted: true})
+	f.createItem(t, model.CreateItemRequest{Name: "db", Content: `{"port": 80}`, ContentType: "json"})
+	mustOK(t)(SetSchema(f.alice, f.project.Id, basic.Resource_Type_PROJECT, portSchema))
+	mustOK(t)(AuthorizeProject(f.alice, f.project.Id, model.AuthorizeProjectRequest{Type: "modifier", UserId: f.bob.Id, Environment: "prod"}))
+	mustOK(t)(AuthorizeProject(f.alice, f.project.Id, model.AuthorizeProjectRequest{Type: "viewer", UserId: f.bob.Id, Environment: "dev"}))
+
+	if _, err := ExportOrg(f.bob, f.org.Id, true); err == nil {
+		t.Error("ExportOrg() without org privilege should fail")
+	}
+	archive, err := ExportOrg(f.alice, f.org.Id, true)
+	if err != nil {
+		t.Fatalf("ExportOrg() error = %v", err)
+	}
+	if archive.Name != "acme" || !archive.Private || len(archive.Projects) != 1 {
+		t.Fatalf("archive = %+v, want private acme with one project", archive)
+	}
+	project := archive.Projects[0]
+	if project.Name != "web" || len(project.Environments) != 2 || project.Schema == "" {
+		t.Errorf("project = %+v, want web with two environments and a schema", project)
+	}
+	contents := make(map[string]string)
+	for _, item := range project.Items {
+		contents[item.Name+"@"+item.Environment] = item.Content
+	}
+	if contents["db@prod"] != "a=prod" || contents["db@"] != `{"port": 80}` {
+		t.Errorf("item contents = %v, want plaintext for both environments", contents)
+	}
+	bobGrants := make(map[string]string)
+	for _, privilege := range project.Privileges {
+		if privilege.User == "bob" {
+			bobGrants[privilege.Environment] = privilege.Type
+		}
+	}
+	if len(bobGrants) != 2 || bobGrants["prod"] != "modifier" || bobGrants["dev"] != "viewer" {
+		t.Errorf("bob's grants = %v, want modifier on prod and viewer on dev", bobGrants)
+	}
+
+	archive, err = ExportOrg(f.alice, f.org.Id, false)
+	if err != nil {
+		t.Fatalf("ExportOrg() error = %v", err)
+	}
+	if len(archive.Privileges) != 0 || len(archive.Projects[0].Privileges) != 0 {
+		t.Error("ExportOrg() without privileges should not export grants")
+	}
+}
+
+func TestImportOrg(t *testing.T) {
+	f := newFixture(t)
+	f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=prod", Environment: "prod", Encrypted: true})
+	mustOK(t)(AuthorizeProject(f.alice, f.project.Id, model.AuthorizeProjectRequest{Type: "modifier", UserId: f.bob.Id, Environment: "prod"}))
+	archive, err := ExportOrg(f.alice, f.org.Id, true)
+	if err != nil {
+		t.Fatalf("ExportOrg() error = %v", err)
+	}
+	imported := func(conflict string) gin.H {
+		t.Helper()
+		return mustOK(t)(ImportOrg(f.alice, model.ImportOrgQuery{Name: "copy", Conflict: conflict, Privileges: true}, archive))
+	}
+	pullCopy := func(user *model.User) (gin.H, error) {
+		return Pull(user, "", "", "prod", "", "copy", "web", "db")
+	}
+
+	data := imported("")
+	if created := data["created"].([]string); len(created) == 0 {
+		t.Error("ImportOrg() created nothing")
+	}
+	for _, user := range []*model.User{f.alice, f.bob} {
+		data := mustOK(t)(pullCopy(user))
+		if data["content"] != "a=prod" {
+			t.Errorf("Pull() by %s content = %v, want a=prod", user.Name, data["content"])
+		}
+	}
+	var copied *model.Item
+	view(t, func(tx repo.Tx) error {
+		org, err := tx.Orgs().GetByName("copy")
+		if err != nil {
+			return err
+		}
+		project, err := tx.Projects().GetByParentIdAndName(org.Id, "copy.web")
+		if err != nil {
+			return err
+		}
+		copied, err = tx.Items().GetByParentIdAndName(project.Id, "copy.web.db", "prod")
+		return err
+	})
+	if copied.Encrypted != 1 {
+		t.Error("imported item should stay encrypted")
+	}
+
+	// 内容相同时重复导入不算冲突
+	if data = imported(""); len(data["created"].([]string)) != 0 || len(data["updated"].([]string)) != 0 {
+		t.Errorf("re-import = %v, want everything unchanged", data)
+	}
+	archive.Projects[0].Items[0].Content = "a=changed"
+	if _, err = ImportOrg(f.alice, model.ImportOrgQuery{Name: "copy", Privileges: true}, archive); err == nil {
+		t.Error("ImportOrg() with conflict=fail should fail on changed content")
+	}
+	if data = imported(basic.Import_Conflict_SKIP); len(data["skipped"].([]string)) != 1 {
+		t.Errorf("skipped = %v, want the changed item", data["skipped"])
+	}
+	if got := mustOK(t)(pullCopy(f.alice))["content"]; got != "a=prod" {
+		t.Errorf("Pull() after skip content = %v, want a=prod", got)
+	}
+	if data = imported(basic.Import_Conflict_OVERWRITE); len(data["updated"].([]string)) != 1 {
+		t.Errorf("updated = %v, want the changed item", data["updated"])
+	}
+	if got := mustOK(t)(pullCopy(f.alice))["content"]; got != "a=changed" {
+		t.Errorf("Pull() after overwrite content = %v, want a=changed", got)
+	}
+
+	for name, bad := range map[string]func(a *model.OrgArchive){
+		"format version":      func(a *model.OrgArchive) { a.FormatVersion = 0 },
+		"dotted project":      func(a *model.OrgArchive) { a.Projects[0].Name = "a.b" },
+		"unknown environment": func(a *model.OrgArchive) { a.Projects[0].Items[0].Environment = "staging" },
+	} {
+		broken, err := ExportOrg(f.alice, f.org.Id, false)
+		if err != nil {
+			t.Fatalf("ExportOrg() error = %v", err)
+		}
+		bad(broken)
+		if _, err = ImportOrg(f.alice, model.ImportOrgQuery{Name: "broken"}, broken); err == nil {
+			t.Errorf("ImportOrg() with bad %s should fail", name)
+		}
+	}
+	if _, err = ImportOrg(f.alice, model.ImportOrgQuery{Conflict: "merge"}, archive); err == nil {
+		t.Error("ImportOrg() with an unknown conflict mode should fail")
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/utils"
+)
+
+func orgSnapshot(org *model.Org) gin.H {
+	return gin.H{"name": org.Name, "visibility": org.Visibility}
+}
+
+func projectSnapshot(project *model.Project) gin.H {
+	return gin.H{"name": project.Name, "visibility": project.Visibility}
+}
+
+func itemSnapshot(item *model.Item) gin.H {
+	return gin.H{
+		"name":         item.Name,
+		"visibility":   item.Visibility,
+		"content":      maskContent(item, item.Content),
+		"version_id":   item.CurrentVersionId,
+		"environment":  item.Environment,
+		"content_type": item.ContentType,
+		"encrypted":    item.Encrypted,
+	}
+}
+
+func privilegeSnapshot(targetUser *model.User, priType int, environment string) gin.H {
+	return gin.H{
+		"user_id":     targetUser.Id,
+		"user_name":   targetUser.Name,
+		"type":        utils.FormatPrivilegeType(priType),
+		"environment": environment,
+	}
+}
+
+func marshalAuditValue(value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// 审计日志与变更写在同一个事务中, 变更回滚时日志一并回滚
+func recordAudit(tx repo.Tx, user *model.User, audit *model.Audit, before, after interface{}) error {
+	var err error
+	audit.UserId = user.Id
+	if audit.Before, err = marshalAuditValue(before); err != nil {
+		return err
+	}
+	if audit.After, err = marshalAuditValue(after); err != nil {
+		return err
+	}
+	_, err = tx.Audits().Create(audit)
+	return err
+}
+
+func recordProjectAudit(tx repo.Tx, user *model.User, action string, project *model.Project, targetUserId int, before, after interface{}) error {
+	return recordAudit(tx, user, &model.Audit{
+		OrgId:        project.ParentId,
+		Action:       action,
+		ResourceType: basic.Resource_Type_PROJECT,
+		ResourceId:   project.Id,
+		ResourceName: project.Name,
+		TargetUserId: targetUserId,
+	}, before, after)
+}
+
+func recordItemAudit(tx repo.Tx, user *model.User, action string, item *model.Item, targetUserId int, before, after interface{}) error {
+	project, err := tx.Projects().GetById(item.ParentId)
+	if err != nil {
+		return err
+	}
+	if project == nil {
+		return errors.New("item所属项目不存在")
+	}
+	return recordAudit(tx, user, &model.Audit{
+		OrgId:        project.ParentId,
+		Action:       action,
+		ResourceType: basic.Resource_Type_ITEM,
+		ResourceId:   item.Id,
+		ResourceName: item.Name,
+		TargetUserId: targetUserId,
+	}, before, after)
+}
+
+func ListAudit(user *model.User, query model.AuditQuery) (gin.H, error) {
+	resType := 0
+	if query.ResourceType != "" {
+		var err error
+		if resType, err = utils.ParseResourceType(query.ResourceType); err != nil {
+			return nil, err
+		}
+	}
+	query.Page, query.PageSize = utils.NormalizePage(query.Page, query.PageSize)
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	flag, err := repo.ValidateForUserModifyOrg(tx, user, query.OrgId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限查看该组织的审计日志")
+	}
+	audits, total, err := tx.Audits().List(query, resType, (query.Page-1)*query.PageSize, query.PageSize)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	var userIds []int
+	for _, audit := range *audits {
+		userIds = append(userIds, audit.UserId)
+		if audit.TargetUserId != 0 {
+			userIds = append(userIds, audit.TargetUserId)
+		}
+	}
+	users, err := tx.Users().ListByIds(userIds)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": utils.GetPageInfo(total, query.Page, query.PageSize, utils.GetAuditInfo(audits, users)),
+	}, nil
+}
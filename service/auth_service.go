@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/utils"
+)
+
+var ErrUnauthorized = errors.New("未登录或认证失败")
+
+func AuthenticateBySession(token string) (*model.User, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	session, err := tx.Sessions().GetByTokenHash(utils.HashToken(token))
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if session == nil {
+		_ = tx.Rollback()
+		return nil, ErrUnauthorized
+	}
+	user, err := tx.Users().GetById(session.UserId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, ErrUnauthorized
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return user, nil
+}
+
+// API token认证: 返回的user携带token, 鉴权时据此限制范围和只读
+func AuthenticateByToken(raw string) (*model.User, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	token, err := tx.Tokens().GetByTokenHash(utils.HashToken(raw))
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if token == nil {
+		_ = tx.Rollback()
+		return nil, ErrUnauthorized
+	}
+	user, err := tx.Users().GetById(token.UserId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, ErrUnauthorized
+	}
+	lastUsedBefore := time.Now().Add(-basic.TOKEN_LAST_USED_INTERVAL_SECONDS * time.Second)
+	if token.LastUsedAt == nil || token.LastUsedAt.Before(lastUsedBefore) {
+		if err = tx.Tokens().UpdateLastUsedAt(token.Id); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	user.Token = token
+	return user, nil
+}
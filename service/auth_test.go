@@ -0,0 +1,148 @@
+package service
+
+import (
+	"testing"
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/model"
+)
+
+func TestSession(t *testing.T) {
+	setupStore(t)
+	newUser(t, "alice")
+	if _, _, err := Login(model.LoginRequest{Name: "alice", Password: "wrong-password"}); err == nil {
+		t.Fatal("Login() with a wrong password should fail")
+	}
+	result, token, err := Login(model.LoginRequest{Name: "alice", Password: "pw123456"})
+	mustOK(t)(result, err)
+	user, err := AuthenticateBySession(token)
+	if err != nil || user.Name != "alice" {
+		t.Fatalf("AuthenticateBySession() = %v, %v, want alice", user, err)
+	}
+	if user.Token != nil {
+		t.Error("session user should not carry an API token")
+	}
+	mustOK(t)(Logout(token))
+	if _, err = AuthenticateBySession(token); err != ErrUnauthorized {
+		t.Errorf("AuthenticateBySession() after logout error = %v, want ErrUnauthorized", err)
+	}
+}
+
+// 创建API token并以它认证, 返回携带token的用户
+func newTokenUser(t *testing.T, user *model.User, req model.CreateTokenRequest) *model.User {
+	t.Helper()
+	data := mustOK(t)(CreateToken(user, req))
+	tokenUser, err := AuthenticateByToken(data["token"].(string))
+	if err != nil {
+		t.Fatalf("AuthenticateByToken() error = %v", err)
+	}
+	return tokenUser
+}
+
+func TestTokenScope(t *testing.T) {
+	f := newFixture(t)
+	mustOK(t)(CreateProject(f.alice, model.CreateProjectRequest{ParentId: f.org.Id, Name: "api", Private: "true"}))
+	var api *model.Project
+	view(t, func(tx repo.Tx) (err error) {
+		api, err = tx.Projects().GetByParentIdAndName(f.org.Id, "acme.api")
+		return
+	})
+	webItem := f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=1", Encrypted: true})
+	mustOK(t)(CreateItem(f.alice, model.CreateItemRequest{ParentId: api.Id, Name: "db", Content: "a=2", Private: "true"}))
+	var apiItem *model.Item
+	view(t, func(tx repo.Tx) (err error) {
+		apiItem, err = tx.Items().GetByParentIdAndName(api.Id, "acme.api.db", "")
+		return
+	})
+	for _, project := range []*model.Project{f.project, api} {
+		mustOK(t)(AuthorizeProject(f.alice, project.Id, model.AuthorizeProjectRequest{Type: "modifier", UserId: f.bob.Id}))
+	}
+	webToken := newTokenUser(t, f.bob, model.CreateTokenRequest{Name: "web", ResourceType: "project", ResourceId: f.project.Id})
+	webReadOnly := newTokenUser(t, f.bob, model.CreateTokenRequest{Name: "web-ro", ResourceType: "project", ResourceId: f.project.Id, ReadOnly: true})
+	readOnly := newTokenUser(t, f.bob, model.CreateTokenRequest{Name: "ro", ReadOnly: true})
+
+	cases := []struct {
+		name       string
+		user       *model.User
+		resId      int
+		resType    int
+		want       int
+		wantSecret bool
+	}{
+		{"session", f.bob, apiItem.Id, basic.Resource_Type_ITEM, basic.Privilege_Type_MODIFIER, true},
+		{"item in scope", webToken, webItem.Id, basic.Resource_Type_ITEM, basic.Privilege_Type_MODIFIER, true},
+		{"item out of scope", webToken, apiItem.Id, basic.Resource_Type_ITEM, basic.Privilege_Type_NONE, false},
+		{"project out of scope", webToken, api.Id, basic.Resource_Type_PROJECT, basic.Privilege_Type_NONE, false},
+		{"parent org out of scope", webToken, f.org.Id, basic.Resource_Type_ORG, basic.Privilege_Type_NONE, false},
+		{"read-only downgrades modifier", webReadOnly, webItem.Id, basic.Resource_Type_ITEM, basic.Privilege_Type_VIEWER, true},
+		{"read-only is still scoped", webReadOnly, apiItem.Id, basic.Resource_Type_ITEM, basic.Privilege_Type_NONE, false},
+		{"unscoped read-only", readOnly, apiItem.Id, basic.Resource_Type_ITEM, basic.Privilege_Type_VIEWER, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got int
+			var secret bool
+			view(t, func(tx repo.Tx) (err error) {
+				if got, err = repo.ResolvePrivilege(tx, tc.user, tc.resId, tc.resType); err != nil || tc.resType != basic.Resource_Type_ITEM {
+					return
+				}
+				secret, err = repo.ValidateForUserPullSecretItem(tx, tc.user, tc.resId)
+				return
+			})
+			if got != tc.want {
+				t.Errorf("ResolvePrivilege() = %d, want %d", got, tc.want)
+			}
+			if tc.resType == basic.Resource_Type_ITEM && secret != tc.wantSecret {
+				t.Errorf("ValidateForUserPullSecretItem() = %v, want %v", secret, tc.wantSecret)
+			}
+		})
+	}
+
+	content := "a=3"
+	if _, err := UpdateItem(webReadOnly, webItem.Id, model.UpdateItemRequest{Content: &content}); err == nil {
+		t.Error("UpdateItem() with a read-only token should fail")
+	}
+	mustOK(t)(UpdateItem(webToken, webItem.Id, model.UpdateItemRequest{Content: &content}))
+	if _, err := UpdateItem(webToken, apiItem.Id, model.UpdateItemRequest{Content: &content}); err == nil {
+		t.Error("UpdateItem() outside the token scope should fail")
+	}
+}
+
+func TestCreateToken(t *testing.T) {
+	f := newFixture(t)
+	tokenUser := newTokenUser(t, f.alice, model.CreateTokenRequest{Name: "all"})
+	cases := []struct {
+		name    string
+		user    *model.User
+		req     model.CreateTokenRequest
+		wantErr bool
+	}{
+		{"default expiry", f.alice, model.CreateTokenRequest{Name: "a"}, false},
+		{"max expiry", f.alice, model.CreateTokenRequest{Name: "a", ExpireDays: basic.MAX_TOKEN_DAYS}, false},
+		{"expiry over max", f.alice, model.CreateTokenRequest{Name: "a", ExpireDays: basic.MAX_TOKEN_DAYS + 1}, true},
+		{"scope to own org", f.alice, model.CreateTokenRequest{Name: "a", ResourceType: "org", ResourceId: f.org.Id}, false},
+		{"scope without privilege", f.bob, model.CreateTokenRequest{Name: "a", ResourceType: "project", ResourceId: f.project.Id}, true},
+		{"scope to missing project", f.alice, model.CreateTokenRequest{Name: "a", ResourceType: "project", ResourceId: 999}, true},
+		{"item scope", f.alice, model.CreateTokenRequest{Name: "a", ResourceType: "item", ResourceId: 1}, true},
+		{"token creating token", tokenUser, model.CreateTokenRequest{Name: "a"}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := CreateToken(tc.user, tc.req); (err != nil) != tc.wantErr {
+				t.Errorf("CreateToken() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeleteToken(t *testing.T) {
+	f := newFixture(t)
+	data := mustOK(t)(CreateToken(f.alice, model.CreateTokenRequest{Name: "ci"}))
+	if _, err := DeleteToken(f.bob, data["id"].(int)); err == nil {
+		t.Error("DeleteToken() of another user's token should fail")
+	}
+	mustOK(t)(DeleteToken(f.alice, data["id"].(int)))
+	if _, err := AuthenticateByToken(data["token"].(string)); err != ErrUnauthorized {
+		t.Errorf("AuthenticateByToken() after delete error = %v, want ErrUnauthorized", err)
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"zoe/basic"
+	"zoe/cache"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/notify"
+	"zoe/utils"
+)
+
+func SaveDraft(user *model.User, itemId int, req model.SaveDraftRequest) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := getItemForModify(tx, user, itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = utils.ValidateContent(item.ContentType, req.Content); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	before, err := tx.Drafts().GetByItemId(item.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	content, err := sealContent(item, req.Content)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = tx.Drafts().Save(item.Id, content, user.Id); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	var beforeValue interface{}
+	if before != nil {
+		beforeValue = gin.H{"content": maskContent(item, before.Content)}
+	}
+	err = recordItemAudit(tx, user, basic.Audit_Action_SAVE_DRAFT, item, 0, beforeValue, gin.H{"content": maskContent(item, req.Content)})
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+	}, nil
+}
+
+func DiscardDraft(user *model.User, itemId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := getItemForModify(tx, user, itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	draft, err := tx.Drafts().GetByItemId(item.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if draft == nil {
+		_ = tx.Rollback()
+		return nil, errors.New("该item没有草稿")
+	}
+	if err = tx.Drafts().Delete(item.Id); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = recordItemAudit(tx, user, basic.Audit_Action_DISCARD_DRAFT, item, 0, gin.H{"content": maskContent(item, draft.Content)}, nil)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+	}, nil
+}
+
+func PublishItem(user *model.User, itemId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := getItemForModify(tx, user, itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	gray, err := tx.Grays().GetRunningByItemId(item.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if gray != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("该item正在灰度发布中, 请先全量或终止灰度")
+	}
+	draft, err := tx.Drafts().GetByItemId(item.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if draft == nil {
+		_ = tx.Rollback()
+		return nil, errors.New("该item没有待发布的草稿")
+	}
+	// 加密item的草稿是密文, 发布时原样写入, 只对明文做校验
+	plaintext, err := openContent(item, draft.Content)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = utils.ValidateContent(item.ContentType, plaintext); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = checkContentSchema(tx, item.ParentId, item.Id, item.ContentType, plaintext); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = tx.Items().UpdateContent(item.Id, draft.Content); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	versionId, err := createVersion(tx, user.Id, item.Id, basic.Resource_Type_ITEM, item.Name, draft.Content, item.Visibility, item.Encrypted)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = tx.Drafts().Delete(item.Id); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	after := *item
+	after.Content = draft.Content
+	after.CurrentVersionId = versionId
+	if err = recordItemAudit(tx, user, basic.Audit_Action_PUBLISH, item, 0, itemSnapshot(item), itemSnapshot(&after)); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(utils.GetItemKey(item.Name, item.Environment))
+	notify.ItemHub.Publish(utils.GetItemKey(item.Name, item.Environment))
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": gin.H{
+			"id":         item.Id,
+			"version_id": versionId,
+		},
+	}, nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+	"zoe/keyring"
+	"zoe/model"
+)
+
+func TestDraftPublishAndRollback(t *testing.T) {
+	cases := []struct {
+		name      string
+		encrypted bool
+	}{
+		{"plain", false},
+		{"encrypted", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture(t)
+			item := f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=1", Encrypted: tc.encrypted})
+			first := item.CurrentVersionId
+			pulled := func(want string) {
+				t.Helper()
+				if got := f.pull(t, f.alice, "db", "")["content"]; got != want {
+					t.Errorf("Pull() content = %v, want %s", got, want)
+				}
+			}
+			pulled("a=1")
+
+			if _, err := PublishItem(f.alice, item.Id); err == nil {
+				t.Error("PublishItem() without a draft should fail")
+			}
+			if _, err := SaveDraft(f.bob, item.Id, model.SaveDraftRequest{Content: "a=2"}); err == nil {
+				t.Error("SaveDraft() without privilege should fail")
+			}
+			mustOK(t)(SaveDraft(f.alice, item.Id, model.SaveDraftRequest{Content: "a=2"}))
+			pulled("a=1")
+
+			data := mustOK(t)(PublishItem(f.alice, item.Id))
+			pulled("a=2")
+			published := f.item(t, "db", "")
+			if published.CurrentVersionId != data["version_id"] {
+				t.Errorf("CurrentVersionId = %d, want %v", published.CurrentVersionId, data["version_id"])
+			}
+			if encrypted := keyring.KeyIdOf(published.Content) != ""; encrypted != tc.encrypted {
+				t.Errorf("stored content encrypted = %v, want %v", encrypted, tc.encrypted)
+			}
+			if _, err := PublishItem(f.alice, item.Id); err == nil {
+				t.Error("PublishItem() should fail once the draft is published")
+			}
+
+			if _, err := RollbackItem(f.alice, item.Id, model.RollbackItemRequest{VersionId: published.CurrentVersionId}); err == nil {
+				t.Error("RollbackItem() to the current version should fail")
+			}
+			data = mustOK(t)(RollbackItem(f.alice, item.Id, model.RollbackItemRequest{VersionId: first}))
+			if data["rollback_from"] != first {
+				t.Errorf("rollback_from = %v, want %d", data["rollback_from"], first)
+			}
+			pulled("a=1")
+			if got := f.item(t, "db", "").CurrentVersionId; got != data["version_id"] {
+				t.Errorf("CurrentVersionId after rollback = %d, want %v", got, data["version_id"])
+			}
+		})
+	}
+}
+
+func TestDraftValidation(t *testing.T) {
+	f := newFixture(t)
+	item := f.createItem(t, model.CreateItemRequest{Name: "app", Content: `{"port": 80}`, ContentType: "json"})
+	if _, err := SaveDraft(f.alice, item.Id, model.SaveDraftRequest{Content: "{"}); err == nil {
+		t.Error("SaveDraft() with invalid json should fail")
+	}
+	mustOK(t)(SaveDraft(f.alice, item.Id, model.SaveDraftRequest{Content: `{"port": 81}`}))
+	mustOK(t)(DiscardDraft(f.alice, item.Id))
+	if _, err := DiscardDraft(f.alice, item.Id); err == nil {
+		t.Error("DiscardDraft() without a draft should fail")
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"regexp"
+	"zoe/basic"
+	"zoe/cache"
+	"zoe/dao/repo"
+	"zoe/model"
+)
+
+var environmentNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+func validateEnvironmentName(name string) error {
+	if len(name) > basic.MAX_ENVIRONMENT_NAME_LENGTH {
+		return errors.New("环境名长度过长")
+	}
+	if !environmentNamePattern.MatchString(name) {
+		return errors.New("环境名只能包含小写字母、数字、_和-")
+	}
+	return nil
+}
+
+// 空串表示默认环境, 总是存在
+func checkEnvironment(tx repo.Tx, projectId int, environment string) error {
+	if environment == "" {
+		return nil
+	}
+	env, err := tx.Environments().GetByProjectIdAndName(projectId, environment)
+	if err != nil {
+		return err
+	}
+	if env == nil {
+		return errors.New("目标环境不存在")
+	}
+	return nil
+}
+
+func getEnvironmentInfo(envs *[]model.Environment) []gin.H {
+	data := make([]gin.H, 0, len(*envs))
+	for _, env := range *envs {
+		data = append(data, gin.H{
+			"id":         env.Id,
+			"name":       env.Name,
+			"created_at": env.CreateAt,
+		})
+	}
+	return data
+}
+
+func CreateEnvironment(user *model.User, projectId int, req model.CreateEnvironmentRequest) (gin.H, error) {
+	if err := validateEnvironmentName(req.Name); err != nil {
+		return nil, err
+	}
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	project, err := tx.Projects().GetById(projectId)
+	if project == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标项目不存在")
+	}
+	flag, err := repo.ValidateForUserModifyProject(tx, user, projectId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限修改该项目")
+	}
+	existing, err := tx.Environments().GetByProjectIdAndName(projectId, req.Name)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if existing != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("该环境已经存在")
+	}
+	id, err := tx.Environments().Create(projectId, req.Name)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = recordProjectAudit(tx, user, basic.Audit_Action_CREATE_ENVIRONMENT, project, 0,
+		nil, gin.H{"environment": req.Name})
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": gin.H{
+			"id":         id,
+			"name":       req.Name,
+			"project_id": projectId,
+		},
+	}, nil
+}
+
+func ListEnvironment(user *model.User, projectId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	project, err := tx.Projects().GetById(projectId)
+	if project == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标项目不存在")
+	}
+	flag, err := repo.ValidateForUserViewAnyEnvironment(tx, user, projectId, basic.Resource_Type_PROJECT)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限查看该项目")
+	}
+	envs, err := tx.Environments().ListByProjectId(projectId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": getEnvironmentInfo(envs),
+	}, nil
+}
+
+// 环境下还有item时不允许删除, 避免误删配置
+func DeleteEnvironment(user *model.User, projectId int, name string) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	project, err := tx.Projects().GetById(projectId)
+	if project == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标项目不存在")
+	}
+	flag, err := repo.ValidateForUserModifyProject(tx, user, projectId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限修改该项目")
+	}
+	env, err := tx.Environments().GetByProjectIdAndName(projectId, name)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if env == nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标环境不存在")
+	}
+	items, err := tx.Items().ListByParentIdAndEnvironment(projectId, name)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if len(*items) > 0 {
+		_ = tx.Rollback()
+		return nil, errors.New("该环境下还有item, 不能删除")
+	}
+	if err = tx.Privileges().DeleteByResourceAndEnvironment(projectId, basic.Resource_Type_PROJECT, name); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = tx.Environments().Delete(env.Id); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = recordProjectAudit(tx, user, basic.Audit_Action_DELETE_ENVIRONMENT, project, 0,
+		gin.H{"environment": name}, nil)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(project.Name)
+	return gin.H{"code": 0, "msg": "OK"}, nil
+}
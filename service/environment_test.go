@@ -0,0 +1,71 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"strings"
+	"testing"
+	"zoe/basic"
+	"zoe/model"
+)
+
+func TestCreateEnvironment(t *testing.T) {
+	f := newFixture(t)
+	cases := []struct {
+		name    string
+		user    *model.User
+		env     string
+		wantErr bool
+	}{
+		{"valid", f.alice, "staging-2", false},
+		{"duplicate", f.alice, "prod", true},
+		{"upper case", f.alice, "Prod", true},
+		{"dot", f.alice, "pre.prod", true},
+		{"too long", f.alice, strings.Repeat("a", basic.MAX_ENVIRONMENT_NAME_LENGTH+1), true},
+		{"without privilege", f.bob, "qa", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CreateEnvironment(tc.user, f.project.Id, model.CreateEnvironmentRequest{Name: tc.env})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("CreateEnvironment() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+	if _, err := CreateItem(f.alice, model.CreateItemRequest{ParentId: f.project.Id, Name: "db", Environment: "qa"}); err == nil {
+		t.Error("CreateItem() in a missing environment should fail")
+	}
+}
+
+func TestEnvironmentItems(t *testing.T) {
+	f := newFixture(t)
+	f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=prod", Environment: "prod"})
+	f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=default"})
+	if _, err := CreateItem(f.alice, model.CreateItemRequest{ParentId: f.project.Id, Name: "db", Environment: "prod"}); err == nil {
+		t.Error("CreateItem() should fail for a name taken in the same environment")
+	}
+	for _, env := range []string{"prod", ""} {
+		want := "a=default"
+		if env != "" {
+			want = "a=" + env
+		}
+		if got := f.pull(t, f.alice, "db", env)["content"]; got != want {
+			t.Errorf("Pull() in %q content = %v, want %s", env, got, want)
+		}
+	}
+	if _, err := Pull(f.alice, "", "", "dev", "", "acme", "web", "db"); err == nil {
+		t.Error("Pull() should not fall back to the default environment")
+	}
+
+	if _, err := DeleteEnvironment(f.alice, f.project.Id, "prod"); err == nil {
+		t.Error("DeleteEnvironment() should fail while it has items")
+	}
+	mustOK(t)(DeleteEnvironment(f.alice, f.project.Id, "dev"))
+	result, err := ListEnvironment(f.alice, f.project.Id)
+	mustOK(t)(result, err)
+	if envs := result["data"].([]gin.H); len(envs) != 1 || envs[0]["name"] != "prod" {
+		t.Errorf("ListEnvironment() = %v, want only prod", envs)
+	}
+	if _, err = DeleteEnvironment(f.alice, f.project.Id, "dev"); err == nil {
+		t.Error("DeleteEnvironment() of a missing environment should fail")
+	}
+}
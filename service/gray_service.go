@@ -0,0 +1,281 @@
+package service
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"zoe/basic"
+	"zoe/cache"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/notify"
+	"zoe/utils"
+)
+
+func graySnapshot(gray *model.GrayRelease) gin.H {
+	return gin.H{
+		"version_id": gray.VersionId,
+		"client_ids": gray.ClientIds,
+		"ip_ranges":  gray.IpRanges,
+		"percentage": gray.Percentage,
+	}
+}
+
+func getGrayInfo(gray *model.GrayRelease) gin.H {
+	var status string
+	if gray.Status == basic.Gray_Status_RUNNING {
+		status = "running"
+	} else if gray.Status == basic.Gray_Status_PROMOTED {
+		status = "promoted"
+	} else if gray.Status == basic.Gray_Status_ABORTED {
+		status = "aborted"
+	} else {
+		status = "unknown_gray_status"
+	}
+	return gin.H{
+		"id":         gray.Id,
+		"item_id":    gray.ItemId,
+		"version_id": gray.VersionId,
+		"client_ids": utils.SplitList(gray.ClientIds),
+		"ip_ranges":  utils.SplitList(gray.IpRanges),
+		"percentage": gray.Percentage,
+		"status":     status,
+	}
+}
+
+func StartGray(user *model.User, itemId int, req model.StartGrayRequest) (gin.H, error) {
+	if req.Percentage < 0 || req.Percentage > 100 {
+		return nil, errors.New("灰度百分比必须在0到100之间")
+	}
+	if err := utils.ValidateClientIds(req.ClientIds); err != nil {
+		return nil, err
+	}
+	ipRanges, err := utils.NormalizeIpRanges(req.IpRanges)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := getItemForModify(tx, user, itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	gray, err := tx.Grays().GetRunningByItemId(itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if gray != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("该item已有进行中的灰度发布")
+	}
+	if err = utils.ValidateContent(item.ContentType, req.Content); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = checkContentSchema(tx, item.ParentId, item.Id, item.ContentType, req.Content); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	content, err := sealContent(item, req.Content)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	versionId, err := tx.Versions().Create(item.Name, content, item.Id, basic.Resource_Type_ITEM, item.Visibility, item.Encrypted, user.Id, 0)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	_, err = tx.Grays().Create(item.Id, versionId, strings.Join(utils.MergeList(req.ClientIds, nil), ","),
+		strings.Join(ipRanges, ","), req.Percentage, user.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	gray, err = tx.Grays().GetRunningByItemId(itemId)
+	if gray == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("创建灰度发布失败")
+	}
+	after := graySnapshot(gray)
+	after["content"] = maskContent(item, req.Content)
+	if err = recordItemAudit(tx, user, basic.Audit_Action_START_GRAY, item, 0, nil, after); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(utils.GetItemKey(item.Name, item.Environment))
+	notify.ItemHub.Publish(utils.GetItemKey(item.Name, item.Environment))
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": getGrayInfo(gray),
+	}, nil
+}
+
+func WidenGray(user *model.User, itemId int, req model.UpdateGrayRequest) (gin.H, error) {
+	if req.Percentage < 0 || req.Percentage > 100 {
+		return nil, errors.New("灰度百分比必须在0到100之间")
+	}
+	if err := utils.ValidateClientIds(req.ClientIds); err != nil {
+		return nil, err
+	}
+	ipRanges, err := utils.NormalizeIpRanges(req.IpRanges)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := getItemForModify(tx, user, itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	gray, err := tx.Grays().GetRunningByItemId(itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if gray == nil {
+		_ = tx.Rollback()
+		return nil, errors.New("该item没有进行中的灰度发布")
+	}
+	if req.Percentage < gray.Percentage {
+		_ = tx.Rollback()
+		return nil, errors.New("灰度百分比不能缩小")
+	}
+	before := graySnapshot(gray)
+	gray.ClientIds = strings.Join(utils.MergeList(utils.SplitList(gray.ClientIds), req.ClientIds), ",")
+	gray.IpRanges = strings.Join(utils.MergeList(utils.SplitList(gray.IpRanges), ipRanges), ",")
+	gray.Percentage = req.Percentage
+	if err = tx.Grays().UpdateRule(gray.Id, gray.ClientIds, gray.IpRanges, gray.Percentage); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = recordItemAudit(tx, user, basic.Audit_Action_WIDEN_GRAY, item, 0, before, graySnapshot(gray)); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(utils.GetItemKey(item.Name, item.Environment))
+	notify.ItemHub.Publish(utils.GetItemKey(item.Name, item.Environment))
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": getGrayInfo(gray),
+	}, nil
+}
+
+func PromoteGray(user *model.User, itemId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := getItemForModify(tx, user, itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	gray, err := tx.Grays().GetRunningByItemId(itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if gray == nil {
+		_ = tx.Rollback()
+		return nil, errors.New("该item没有进行中的灰度发布")
+	}
+	version, err := tx.Versions().GetById(gray.VersionId)
+	if version == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("灰度版本不存在")
+	}
+	if err = tx.Items().UpdateContent(item.Id, version.Content); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = tx.Items().UpdateCurrentVersionId(item.Id, version.Id); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = tx.Grays().UpdateStatus(gray.Id, basic.Gray_Status_PROMOTED); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	after := *item
+	after.Content = version.Content
+	after.CurrentVersionId = version.Id
+	if err = recordItemAudit(tx, user, basic.Audit_Action_PROMOTE_GRAY, item, 0, itemSnapshot(item), itemSnapshot(&after)); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(utils.GetItemKey(item.Name, item.Environment))
+	notify.ItemHub.Publish(utils.GetItemKey(item.Name, item.Environment))
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": gin.H{
+			"id":         item.Id,
+			"version_id": version.Id,
+		},
+	}, nil
+}
+
+func AbortGray(user *model.User, itemId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := getItemForModify(tx, user, itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	gray, err := tx.Grays().GetRunningByItemId(itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if gray == nil {
+		_ = tx.Rollback()
+		return nil, errors.New("该item没有进行中的灰度发布")
+	}
+	if err = tx.Grays().UpdateStatus(gray.Id, basic.Gray_Status_ABORTED); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = recordItemAudit(tx, user, basic.Audit_Action_ABORT_GRAY, item, 0, graySnapshot(gray), nil); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(utils.GetItemKey(item.Name, item.Environment))
+	notify.ItemHub.Publish(utils.GetItemKey(item.Name, item.Environment))
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+	}, nil
+}
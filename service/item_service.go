@@ -0,0 +1,616 @@
+package service
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"zoe/basic"
+	"zoe/cache"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/notify"
+	"zoe/utils"
+)
+
+func getItemForModify(tx repo.Tx, user *model.User, itemId int) (*model.Item, error) {
+	item, err := tx.Items().GetById(itemId)
+	if item == nil || err != nil {
+		return nil, errors.New("目标item不存在")
+	}
+	flag, err := repo.ValidateForUserModifyItem(tx, user, itemId)
+	if err != nil || !flag {
+		return nil, errors.New("用户无权限修改item")
+	}
+	return item, nil
+}
+
+func CreateItem(user *model.User, req model.CreateItemRequest) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	project, err := tx.Projects().GetById(req.ParentId)
+	if project == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标项目不存在")
+	}
+	flag, err := repo.ValidateForUserModifyProjectInEnvironment(tx, user, project.Id, req.Environment)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限创建item")
+	}
+	if strings.Contains(req.Name, ".") {
+		_ = tx.Rollback()
+		return nil, errors.New("item名不能包含.")
+	}
+	name := project.Name + "." + req.Name
+	if len(name) >= basic.MAX_RESOURCE_NAME_LENGTH {
+		_ = tx.Rollback()
+		return nil, errors.New("item名长度过长")
+	}
+	if err = checkEnvironment(tx, project.Id, req.Environment); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	contentType, err := utils.ParseContentType(req.ContentType)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = utils.ValidateContent(contentType, req.Content); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = checkContentSchema(tx, project.Id, 0, contentType, req.Content); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	existing, err := tx.Items().GetByParentIdAndName(project.Id, name, req.Environment)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if existing != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("该item已经存在")
+	}
+	visibility := utils.ParseVisibility(req.Private)
+	encrypted := 0
+	if req.Encrypted {
+		encrypted = 1
+	}
+	content, err := sealContent(&model.Item{Encrypted: encrypted}, req.Content)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	id, err := tx.Items().Create(name, content, req.Environment, contentType, visibility, encrypted, project.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("用户创建item失败")
+	}
+	versionId, err := createVersion(tx, user.Id, id, basic.Resource_Type_ITEM, name, content, visibility, encrypted)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = repo.AddWithCheck(tx, user.UserHash, name, id,
+		basic.Resource_Type_ITEM, user.Id, basic.Privilege_Type_MODIFIER, visibility)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	item := &model.Item{Id: id, Name: name, ParentId: project.Id, Visibility: visibility, Content: content,
+		CurrentVersionId: versionId, Environment: req.Environment, ContentType: contentType, Encrypted: encrypted}
+	if err = recordItemAudit(tx, user, basic.Audit_Action_CREATE, item, 0, nil, itemSnapshot(item)); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	var visibilityStr string
+	if visibility == 0 {
+		visibilityStr = "private"
+	} else {
+		visibilityStr = "public"
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": gin.H{
+			"id":           id,
+			"name":         name,
+			"parent_id":    project.Id,
+			"visibility":   visibilityStr,
+			"version_id":   versionId,
+			"environment":  req.Environment,
+			"content_type": contentType,
+			"encrypted":    req.Encrypted,
+		},
+	}, nil
+}
+
+func UpdateItem(user *model.User, itemId int, req model.UpdateItemRequest) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := tx.Items().GetById(itemId)
+	if item == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标item不存在")
+	}
+	flag, err := repo.ValidateForUserModifyItem(tx, user, itemId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限修改item")
+	}
+	current, err := openContent(item, item.Content)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	// 修改格式时已发布的内容和草稿都要符合新格式
+	contentType := item.ContentType
+	if req.ContentType != nil {
+		if contentType, err = utils.ParseContentType(*req.ContentType); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	if contentType != item.ContentType {
+		if err = utils.ValidateContent(contentType, current); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		draft, err := tx.Drafts().GetByItemId(itemId)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if draft != nil && (req.Content == nil || *req.Content == current) {
+			draftContent, err := openContent(item, draft.Content)
+			if err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+			if err = utils.ValidateContent(contentType, draftContent); err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+	next := *item
+	if req.Encrypted != nil {
+		next.Encrypted = 0
+		if *req.Encrypted {
+			next.Encrypted = 1
+		}
+	}
+	if next.Encrypted != item.Encrypted {
+		gray, err := tx.Grays().GetRunningByItemId(itemId)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if gray != nil {
+			_ = tx.Rollback()
+			return nil, errors.New("该item正在灰度发布中, 请先全量或终止灰度")
+		}
+	}
+	if _, err = setItemEncrypted(tx, item, next.Encrypted); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	// 内容修改只进入草稿, 发布后才对拉取方可见
+	if req.Content != nil && *req.Content != current {
+		if err = utils.ValidateContent(contentType, *req.Content); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		content, err := sealContent(&next, *req.Content)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if err = tx.Drafts().Save(itemId, content, user.Id); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		err = recordItemAudit(tx, user, basic.Audit_Action_SAVE_DRAFT, item, 0, nil, gin.H{"content": maskContent(&next, *req.Content)})
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	// 未传private时保持原有的可见性
+	if req.Private != nil {
+		if err = tx.Items().UpdateVisibility(itemId, utils.ParseVisibility(*req.Private)); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	if err = tx.Items().UpdateContentType(itemId, contentType); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	updated, err := tx.Items().GetById(itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	// 加密状态变更也记录为新版本, 历史版本保持原样
+	if updated.Visibility != item.Visibility || updated.Encrypted != item.Encrypted {
+		versionId, err := createVersion(tx, user.Id, updated.Id, basic.Resource_Type_ITEM, updated.Name, updated.Content,
+			updated.Visibility, updated.Encrypted)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		updated.CurrentVersionId = versionId
+	}
+	if updated.Visibility != item.Visibility || updated.ContentType != item.ContentType || updated.Encrypted != item.Encrypted {
+		err = recordItemAudit(tx, user, basic.Audit_Action_UPDATE, updated, 0, itemSnapshot(item), itemSnapshot(updated))
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(utils.GetItemKey(item.Name, item.Environment))
+	notify.ItemHub.Publish(utils.GetItemKey(item.Name, item.Environment))
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+	}, nil
+}
+
+func DeleteItem(user *model.User, itemId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := tx.Items().GetById(itemId)
+	if item == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标item不存在")
+	}
+	flag, err := repo.ValidateForUserModifyItem(tx, user, itemId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限删除item")
+	}
+	if err = deleteItem(tx, itemId); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = recordItemAudit(tx, user, basic.Audit_Action_DELETE, item, 0, itemSnapshot(item), nil); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(utils.GetItemKey(item.Name, item.Environment))
+	notify.ItemHub.Publish(utils.GetItemKey(item.Name, item.Environment))
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+	}, nil
+}
+
+func SingleItem(user *model.User, itemId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := tx.Items().GetById(itemId)
+	if item == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标item不存在")
+	}
+	flag, err := repo.ValidateForUserViewItem(tx, user, itemId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限查看item")
+	}
+	draft, err := tx.Drafts().GetByItemId(itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	reveal, err := canRevealItem(tx, user, item)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	itemInfo := utils.GetItemInfo(item)
+	if itemInfo["content"], err = revealContent(item, item.Content, reveal); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	var draftContent string
+	if draft != nil {
+		if draftContent, err = revealContent(item, draft.Content, reveal); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if draft != nil {
+		itemInfo["draft"] = gin.H{
+			"content":    draftContent,
+			"user_id":    draft.UserId,
+			"updated_at": draft.UpdatedAt,
+		}
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": itemInfo,
+	}, nil
+}
+
+func RollbackItem(user *model.User, itemId int, req model.RollbackItemRequest) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := tx.Items().GetById(itemId)
+	if item == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标item不存在")
+	}
+	flag, err := repo.ValidateForUserModifyItem(tx, user, itemId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限修改item")
+	}
+	gray, err := tx.Grays().GetRunningByItemId(itemId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if gray != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("该item正在灰度发布中, 请先全量或终止灰度")
+	}
+	version, err := tx.Versions().GetById(req.VersionId)
+	if version == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标版本不存在")
+	}
+	if version.ResourceId != item.Id || version.ResourceType != basic.Resource_Type_ITEM {
+		_ = tx.Rollback()
+		return nil, errors.New("目标版本不属于该item")
+	}
+	if version.Id == item.CurrentVersionId {
+		_ = tx.Rollback()
+		return nil, errors.New("目标版本已是当前版本")
+	}
+	// 回滚后格式可能已经变更, 旧版本内容也要符合当前格式; 旧版本的加密状态也可能与当前不同
+	content, plaintext, err := restoreVersionContent(item, version)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = utils.ValidateContent(item.ContentType, plaintext); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = checkContentSchema(tx, item.ParentId, item.Id, item.ContentType, plaintext); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = tx.Items().UpdateContent(itemId, content); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	versionId, err := tx.Versions().Create(item.Name, content, item.Id, basic.Resource_Type_ITEM,
+		item.Visibility, item.Encrypted, user.Id, version.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = tx.Items().UpdateCurrentVersionId(item.Id, versionId); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	after := *item
+	after.Content = content
+	after.CurrentVersionId = versionId
+	if err = recordItemAudit(tx, user, basic.Audit_Action_ROLLBACK, item, 0, itemSnapshot(item), itemSnapshot(&after)); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(utils.GetItemKey(item.Name, item.Environment))
+	notify.ItemHub.Publish(utils.GetItemKey(item.Name, item.Environment))
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": gin.H{
+			"id":            item.Id,
+			"version_id":    versionId,
+			"rollback_from": version.Id,
+		},
+	}, nil
+}
+
+func AuthorizeItem(user *model.User, itemId int, req model.AuthorizeItemRequest) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := tx.Items().GetById(itemId)
+	if item == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标item不存在")
+	}
+	flag, err := repo.ValidateForUserModifyItem(tx, user, itemId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限修改该item")
+	}
+	targetUser, err := tx.Users().GetById(req.UserId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标用户不存在")
+	}
+	if targetUser.Id == user.Id {
+		_ = tx.Rollback()
+		return nil, errors.New("你不能为自己授权")
+	}
+	priType, err := utils.ParsePrivilegeType(req.Type)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	privilege, err := tx.Privileges().Get(targetUser.UserHash, "", itemId, basic.Resource_Type_ITEM)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	var before interface{}
+	if privilege != nil {
+		before = privilegeSnapshot(targetUser, privilege.PrivilegeType, privilege.Environment)
+		err = tx.Privileges().Update(targetUser.UserHash, "", priType, itemId, basic.Resource_Type_ITEM)
+	} else {
+		err = tx.Privileges().Create(targetUser.UserHash, item.Name, "", itemId, basic.Resource_Type_ITEM,
+			targetUser.Id, priType, item.Visibility)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = recordItemAudit(tx, user, basic.Audit_Action_AUTHORIZE, item, targetUser.Id,
+		before, privilegeSnapshot(targetUser, priType, ""))
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(utils.GetItemKey(item.Name, item.Environment))
+	return gin.H{"code": 0, "msg": "OK"}, nil
+}
+
+func DeleteAuthorizeItem(user *model.User, itemId, userId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := tx.Items().GetById(itemId)
+	if item == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标item不存在")
+	}
+	flag, err := repo.ValidateForUserModifyItem(tx, user, itemId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限修改该item")
+	}
+	targetUser, err := tx.Users().GetById(userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标用户不存在")
+	}
+	privilege, err := tx.Privileges().Get(targetUser.UserHash, "", itemId, basic.Resource_Type_ITEM)
+	if err != nil || privilege == nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标用户无该item的权限")
+	}
+	err = tx.Privileges().Delete(targetUser.UserHash, "", itemId, basic.Resource_Type_ITEM)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = recordItemAudit(tx, user, basic.Audit_Action_DELETE_AUTHORIZE, item, targetUser.Id,
+		privilegeSnapshot(targetUser, privilege.PrivilegeType, privilege.Environment), nil)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(utils.GetItemKey(item.Name, item.Environment))
+	return gin.H{"code": 0, "msg": "OK"}, nil
+}
+
+func ListAuthorizeItem(user *model.User, itemId int, query model.ListQuery) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	item, err := tx.Items().GetById(itemId)
+	if item == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标item不存在")
+	}
+	flag, err := repo.ValidateForUserModifyItem(tx, user, itemId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限查看该item的授权")
+	}
+	data, err := listPrivilegePage(tx, itemId, basic.Resource_Type_ITEM, query)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": data,
+	}, nil
+}
+
+// 级联删除后逐个失效item缓存并通知watch
+func notifyItems(items []model.Item) {
+	for _, item := range items {
+		key := utils.GetItemKey(item.Name, item.Environment)
+		cache.PullCache.Invalidate(key)
+		notify.ItemHub.Publish(key)
+	}
+}
+
+// 删除item及其授权、草稿和灰度发布
+func deleteItem(tx repo.Tx, itemId int) error {
+	if err := tx.Privileges().DeleteByResource(itemId, basic.Resource_Type_ITEM); err != nil {
+		return err
+	}
+	if err := tx.Drafts().Delete(itemId); err != nil {
+		return err
+	}
+	if err := tx.Grays().DeleteByItemId(itemId); err != nil {
+		return err
+	}
+	return tx.Items().Delete(itemId)
+}
@@ -5,51 +5,70 @@ import (
 	"github.com/gin-gonic/gin"
 	"strings"
 	"zoe/basic"
-	"zoe/dao/db"
+	"zoe/cache"
+	"zoe/dao/repo"
 	"zoe/model"
 	"zoe/utils"
 )
 
-func CreateOrg(userHash string, req model.OrgCreateRequest) (gin.H, error) {
-	conn, err := db.DB.Begin()
-	if err != nil {
-		return nil, err
+func CreateOrg(user *model.User, req model.OrgCreateRequest) (gin.H, error) {
+	if user.Token != nil && user.Token.ResourceType != 0 {
+		return nil, errors.New("限定范围的token不能创建组织")
 	}
-	user, err := utils.GetUser(conn, userHash)
+	tx, err := repo.DB.Begin()
 	if err != nil {
-		_ = conn.Rollback()
 		return nil, err
 	}
 	if len(req.Name) >= basic.MAX_RESOURCE_NAME_LENGTH {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("组织名长度过长")
 	}
-	flag, err := db.IsExistingOrgByName(conn, req.Name)
+	if strings.Contains(req.Name, ".") {
+		_ = tx.Rollback()
+		return nil, errors.New("组织名不能包含.")
+	}
+	existing, err := tx.Orgs().GetByName(req.Name)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	if flag {
+	if existing != nil {
+		_ = tx.Rollback()
 		return nil, errors.New("组织已经存在")
 	}
-	visibility := 0
+	visibility := 1
 	if req.Private {
-		visibility = 1
+		visibility = 0
 	}
-	id, err := db.CreateOrg(conn, req.Name, visibility)
+	id, err := tx.Orgs().Create(req.Name, visibility)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	err = db.AddWithCheck(conn, user.UserHash, req.Name, id,
+	if _, err = createVersion(tx, user.Id, id, basic.Resource_Type_ORG, req.Name, "", visibility, 0); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = repo.AddWithCheck(tx, user.UserHash, req.Name, id,
 		basic.Resource_Type_ORG, user.Id, basic.Privilege_Type_MODIFIER, visibility)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	err = conn.Commit()
+	err = recordAudit(tx, user, &model.Audit{
+		OrgId:        id,
+		Action:       basic.Audit_Action_CREATE,
+		ResourceType: basic.Resource_Type_ORG,
+		ResourceId:   id,
+		ResourceName: req.Name,
+	}, nil, gin.H{"name": req.Name, "visibility": visibility})
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
 		return nil, err
 	}
 	var visibilityStr string
@@ -69,322 +88,388 @@ func CreateOrg(userHash string, req model.OrgCreateRequest) (gin.H, error) {
 	}, nil
 }
 
-func UpdateOrg(userHash string, orgId int, req model.OrgUpdateRequest) (gin.H, error) {
-	conn, err := db.DB.Begin()
-	if err != nil {
-		return nil, err
-	}
-	user, err := utils.GetUser(conn, userHash)
+func UpdateOrg(user *model.User, orgId int, req model.OrgUpdateRequest) (gin.H, error) {
+	tx, err := repo.DB.Begin()
 	if err != nil {
-		_ = conn.Rollback()
 		return nil, err
 	}
-	flag, err := db.IsExistingOrgById(conn, orgId)
+	before, err := tx.Orgs().GetById(orgId)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	if !flag {
+	if before == nil {
+		_ = tx.Rollback()
 		return nil, errors.New("不存在的组织")
 	}
 
-	flag, err = db.ValidateForUserModifyOrg(conn, user.UserHash, orgId)
+	flag, err := repo.ValidateForUserModifyOrg(tx, user, orgId)
 	if err != nil || !flag {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("用户无效权限修改该组织")
 	}
-	if err := db.UpdateOrg(conn, orgId, req.Private); err != nil {
-		_ = conn.Rollback()
+	visibility := 1
+	if req.Private {
+		visibility = 0
+	}
+	if err := tx.Orgs().UpdateVisibility(orgId, visibility); err != nil {
+		_ = tx.Rollback()
 		return nil, err
 	}
-	err = conn.Commit()
+	org, err := tx.Orgs().GetById(orgId)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	return gin.H{"code": 0, "msg": "OK"}, nil
-}
-
-func DeleteOrg(userHash string, orgId int) (gin.H, error) {
-	conn, err := db.DB.Begin()
+	if _, err = createVersion(tx, user.Id, org.Id, basic.Resource_Type_ORG, org.Name, "", org.Visibility, 0); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = recordAudit(tx, user, &model.Audit{
+		OrgId:        org.Id,
+		Action:       basic.Audit_Action_UPDATE,
+		ResourceType: basic.Resource_Type_ORG,
+		ResourceId:   org.Id,
+		ResourceName: org.Name,
+	}, orgSnapshot(before), orgSnapshot(org))
 	if err != nil {
+		_ = tx.Rollback()
 		return nil, err
 	}
-	user, err := utils.GetUser(conn, userHash)
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(org.Name)
+	return gin.H{"code": 0, "msg": "OK"}, nil
+}
+
+func DeleteOrg(user *model.User, orgId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
 	if err != nil {
-		_ = conn.Rollback()
 		return nil, err
 	}
-	flag, err := db.ValidateForUserModifyOrg(conn, user.UserHash, orgId)
+	flag, err := repo.ValidateForUserModifyOrg(tx, user, orgId)
 	if err != nil || !flag {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("用户无权限修改该组织")
 	}
-	if err = db.DeleteOrg(conn, orgId); err != nil {
-		_ = conn.Rollback()
+	org, err := tx.Orgs().GetById(orgId)
+	if org == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("不存在的组织")
+	}
+	items, err := deleteOrg(tx, orgId)
+	if err != nil {
+		_ = tx.Rollback()
 		return nil, err
 	}
-	if err = db.DeletePrivilege(conn, orgId, basic.Resource_Type_ORG); err != nil {
-		_ = conn.Rollback()
+	if err = tx.Privileges().DeleteByResource(orgId, basic.Resource_Type_ORG); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = recordAudit(tx, user, &model.Audit{
+		OrgId:        org.Id,
+		Action:       basic.Audit_Action_DELETE,
+		ResourceType: basic.Resource_Type_ORG,
+		ResourceId:   org.Id,
+		ResourceName: org.Name,
+	}, orgSnapshot(org), nil)
+	if err != nil {
+		_ = tx.Rollback()
 		return nil, err
 	}
-	err = conn.Commit()
+	err = tx.Commit()
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
+	cache.PullCache.Invalidate(org.Name)
+	notifyItems(items)
 	return gin.H{
 		"code": 0,
 		"msg":  "OK",
 	}, nil
 }
 
-func ListOrg(userHash string) (gin.H, error) {
-	conn, err := db.DB.Begin()
+// 按组织名搜索, 可按id、name或created_at排序
+func ListOrg(user *model.User, query model.ListQuery) (gin.H, error) {
+	field, desc, err := utils.ParseSort(query.Sort, "id", "name", "created_at")
 	if err != nil {
 		return nil, err
 	}
-	user, err := utils.GetUser(conn, userHash)
+	page, pageSize := utils.NormalizePage(query.Page, query.PageSize)
+	tx, err := repo.DB.Begin()
 	if err != nil {
-		_ = conn.Rollback()
 		return nil, err
 	}
-	privileges, err := db.ListPrivilege(conn, user.UserHash)
+	scopeOrgId, err := getTokenScopeOrgId(tx, user)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	orgNameList := make([]string, len(*privileges))
-	for index, privilege := range *privileges {
-		orgNameList[index] = strings.Split(privilege.ResourceName, ".")[0]
+	orgs, total, err := tx.Orgs().ListPageByUserHash(user.UserHash, scopeOrgId,
+		repo.PageQuery{Name: query.Q, Sort: field, Desc: desc}, (page-1)*pageSize, pageSize)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
 	}
-	orgs, err := db.ListOrg(conn, &orgNameList)
+	err = tx.Commit()
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	data := make([]gin.H, len(*orgs))
-	for index := range data {
-		data[index] = gin.H{"id": (*orgs)[index].Id, "name": (*orgs)[index].Name}
+	items := make([]gin.H, 0, len(*orgs))
+	for _, org := range *orgs {
+		items = append(items, gin.H{"id": org.Id, "name": org.Name})
 	}
 	return gin.H{
 		"code": 0,
 		"msg":  "OK",
-		"data": data,
+		"data": utils.GetPageInfo(total, page, pageSize, items),
 	}, nil
 }
 
-func SingleOrg(userHash string, orgId int) (gin.H, error) {
-	conn, err := db.DB.Begin()
+// 组织详情只带第一页(由page和page_size指定)的project和授权, 完整列表走/org/:org_id/project和/org/:org_id/authorize
+func SingleOrg(user *model.User, orgId int, query model.ListQuery) (gin.H, error) {
+	page, pageSize := utils.NormalizePage(query.Page, query.PageSize)
+	tx, err := repo.DB.Begin()
 	if err != nil {
 		return nil, err
 	}
-	user, err := utils.GetUser(conn, userHash)
+	org, err := tx.Orgs().GetById(orgId)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	flag, err := db.IsExistingOrgById(conn, orgId)
+	if org == nil {
+		_ = tx.Rollback()
+		return nil, errors.New("不存在的组织")
+	}
+	visibleIds, err := listVisibleProjectIds(tx, user, orgId)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	if !flag {
-		return nil, errors.New("不存在的组织")
-	}
-	org, err := db.QueryOrgById(conn, orgId)
+	projects, projectTotal, err := tx.Projects().ListPageByParentId(orgId, visibleIds,
+		repo.PageQuery{NamePrefix: org.Name + ".", Sort: "id"}, (page-1)*pageSize, pageSize)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	flag, err = db.ValidateForUserModifyOrg(conn, user.UserHash, orgId)
+	flag, err := repo.ValidateForUserModifyOrg(tx, user, orgId)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
+	var privilegeInfo []gin.H
+	privilegeTotal := 0
 	if flag {
-		projects, err := db.ListProjectByParentId(conn, orgId)
-		if err != nil {
-			_ = conn.Rollback()
-			return nil, err
-		}
-		privileges, err := db.ListPrivilegeByResource(conn, orgId, basic.Resource_Type_ORG)
+		privileges, total, err := tx.Privileges().ListPageByResource(orgId, basic.Resource_Type_ORG,
+			repo.PageQuery{Sort: "id"}, (page-1)*pageSize, pageSize)
 		if err != nil {
-			_ = conn.Rollback()
+			_ = tx.Rollback()
 			return nil, err
 		}
 		userIds := make([]int, len(*privileges))
 		for index, privilege := range *privileges {
 			userIds[index] = privilege.UserId
 		}
-		users, err := db.ListUserByIds(conn, userIds)
-		if err != nil {
-			_ = conn.Rollback()
-			return nil, err
-		}
-		privilegeInfo := utils.GetPrivilegeUserInfo(privileges, users)
-		projectInfo := utils.GetProjectInfo(projects)
-		orgInfo := utils.GetOrgInfo(projectInfo, privilegeInfo, org)
-		return gin.H{
-			"code": 0,
-			"msg":  "OK",
-			"data": orgInfo,
-		}, nil
-	} else {
-		flag, err = db.ValidateForUserViewOrg(conn, user.UserHash, orgId)
+		users, err := tx.Users().ListByIds(userIds)
 		if err != nil {
-			_ = conn.Rollback()
+			_ = tx.Rollback()
 			return nil, err
 		}
-		if flag {
-			projects, err := db.ListProjectByParentId(conn, orgId)
-			if err != nil {
-				_ = conn.Rollback()
-				return nil, err
-			}
-			projectInfo := utils.GetProjectInfo(projects)
-			orgInfo := utils.GetOrgInfo(projectInfo, nil, org)
-			return gin.H{
-				"code": 0,
-				"msg":  "OK",
-				"data": orgInfo,
-			}, nil
-		} else {
-			publicProjects, privateProject, err := db.ListProjectByVisibility(conn, orgId)
-			if err != nil {
-				_ = conn.Rollback()
-				return nil, err
-			}
-			privileges, err := db.ListPrivilegeByPrefixResourceName(conn, org.Name+".", user.UserHash)
-			if err != nil {
-				_ = conn.Rollback()
-				return nil, err
-			}
-			var projectNames []string
-			var projects []model.Project
-			for _, item := range *privileges {
-				arr := strings.Split(item.ResourceName, ".")
-				projectNames = append(projectNames, arr[1])
-			}
-			for _, item := range *privateProject {
-				for _, name := range projectNames {
-					if name == item.Name {
-						projects = append(projects, item)
-						break
-					}
-				}
-			}
-			if len(*publicProjects) > 0 {
-				projects = append(projects, *publicProjects...)
-			}
-			projectInfo := utils.GetProjectInfo(&projects)
-			orgInfo := utils.GetOrgInfo(projectInfo, nil, org)
-			return gin.H{
-				"code": 0,
-				"msg":  "OK",
-				"data": orgInfo,
-			}, nil
-		}
+		privilegeInfo = utils.GetPrivilegeUserInfo(privileges, users)
+		privilegeTotal = total
+	}
+	if err = tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	orgInfo := utils.GetOrgInfo(utils.GetProjectInfo(projects), privilegeInfo, org)
+	orgInfo["project_total"] = projectTotal
+	if flag {
+		orgInfo["privilege_total"] = privilegeTotal
 	}
+	orgInfo["page"] = page
+	orgInfo["page_size"] = pageSize
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": orgInfo,
+	}, nil
 }
 
-func AuthorizeOrg(userHash string, orgId int, req model.AuthorizeOrgRequest) (gin.H, error) {
-	conn, err := db.DB.Begin()
+func ListAuthorizeOrg(user *model.User, orgId int, query model.ListQuery) (gin.H, error) {
+	tx, err := repo.DB.Begin()
 	if err != nil {
 		return nil, err
 	}
-	user, err := utils.GetUser(conn, userHash)
+	flag, err := repo.ValidateForUserModifyOrg(tx, user, orgId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限查看该组织的授权")
+	}
+	data, err := listPrivilegePage(tx, orgId, basic.Resource_Type_ORG, query)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	flag, err := db.ValidateForUserModifyOrg(conn, user.UserHash, orgId)
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": data,
+	}, nil
+}
+
+func AuthorizeOrg(user *model.User, orgId int, req model.AuthorizeOrgRequest) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	flag, err := repo.ValidateForUserModifyOrg(tx, user, orgId)
 	if err != nil || !flag {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("用户无权限修改该组织")
 	}
-	targetUser, err := db.GetUserByUserId(conn, req.UserId)
+	targetUser, err := tx.Users().GetById(req.UserId)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("目标用户不存在")
 	}
 	if targetUser.Id == user.Id {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("你不能为自己授权")
 	}
-	org, err := db.QueryOrgById(conn, orgId)
+	org, err := tx.Orgs().GetById(orgId)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("不存在灯组织")
 	}
-	privilege, err := db.QueryPrivilegeByUserHash(conn, targetUser.UserHash, orgId, basic.Resource_Type_ORG)
+	privilege, err := tx.Privileges().Get(targetUser.UserHash, req.Environment, orgId, basic.Resource_Type_ORG)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	var priType int
-	if req.Type == "modifier" {
-		priType = basic.Privilege_Type_MODIFIER
-	} else if req.Type == "viewer" {
-		priType = basic.Privilege_Type_VIEWER
-	} else if req.Type == "puller" {
-		priType = basic.Privilege_Type_PULLER
-	} else {
-		return nil, errors.New("非法的授权类型")
+	priType, err := utils.ParsePrivilegeType(req.Type)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
 	}
+	// 环境是项目级的, 组织授权只校验环境名, 对组织下所有项目中的同名环境生效
+	if req.Environment != "" {
+		if err = validateEnvironmentName(req.Environment); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	var before interface{}
 	if privilege != nil {
-		err = db.UpdatePrivilegeByUserHash(conn, targetUser.UserHash, priType, orgId, basic.Resource_Type_ORG)
+		before = privilegeSnapshot(targetUser, privilege.PrivilegeType, privilege.Environment)
+		err = tx.Privileges().Update(targetUser.UserHash, req.Environment, priType, orgId, basic.Resource_Type_ORG)
 	} else {
-		err = db.CreatePrivilege(conn, targetUser.UserHash, org.Name, orgId, basic.Resource_Type_ORG, targetUser.Id, priType, org.Visibility)
+		err = tx.Privileges().Create(targetUser.UserHash, org.Name, req.Environment, orgId, basic.Resource_Type_ORG, targetUser.Id, priType, org.Visibility)
 	}
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	err = conn.Commit()
+	err = recordAudit(tx, user, &model.Audit{
+		OrgId:        org.Id,
+		Action:       basic.Audit_Action_AUTHORIZE,
+		ResourceType: basic.Resource_Type_ORG,
+		ResourceId:   org.Id,
+		ResourceName: org.Name,
+		TargetUserId: targetUser.Id,
+	}, before, privilegeSnapshot(targetUser, priType, req.Environment))
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	// todo 删除拉取的缓存
-
-	return gin.H{"code": 0, "msg": "OK"}, nil
-}
-
-func DeleteAuthorizeOrg(userHash string, orgId, userId int) (gin.H, error) {
-	conn, err := db.DB.Begin()
+	err = tx.Commit()
 	if err != nil {
+		_ = tx.Rollback()
 		return nil, err
 	}
-	user, err := utils.GetUser(conn, userHash)
+	cache.PullCache.Invalidate(org.Name)
+	return gin.H{"code": 0, "msg": "OK"}, nil
+}
+
+// environment指定要删除的是限定在哪个环境的授权, 为空时删除不限定环境的授权
+func DeleteAuthorizeOrg(user *model.User, orgId, userId int, environment string) (gin.H, error) {
+	tx, err := repo.DB.Begin()
 	if err != nil {
-		_ = conn.Rollback()
 		return nil, err
 	}
-	targetUser, err := db.GetUserByUserId(conn, userId)
+	targetUser, err := tx.Users().GetById(userId)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("目标用户不存在")
 	}
-	flag, err := db.ValidateForUserModifyOrg(conn, user.UserHash, orgId)
+	flag, err := repo.ValidateForUserModifyOrg(tx, user, orgId)
 	if err != nil || !flag {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("用户无权限修改该组织")
 	}
-	privilege, err := db.QueryPrivilegeByUserHash(conn, targetUser.UserHash, orgId, basic.Resource_Type_ORG)
+	org, err := tx.Orgs().GetById(orgId)
+	if org == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("不存在的组织")
+	}
+	privilege, err := tx.Privileges().Get(targetUser.UserHash, environment, orgId, basic.Resource_Type_ORG)
 	if err != nil || privilege == nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("目标用户无该组织的权限")
 	}
-	err = db.DeletePrivilegeByUserHash(conn, targetUser.UserHash, orgId, basic.Resource_Type_ORG)
+	err = tx.Privileges().Delete(targetUser.UserHash, environment, orgId, basic.Resource_Type_ORG)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = recordAudit(tx, user, &model.Audit{
+		OrgId:        org.Id,
+		Action:       basic.Audit_Action_DELETE_AUTHORIZE,
+		ResourceType: basic.Resource_Type_ORG,
+		ResourceId:   org.Id,
+		ResourceName: org.Name,
+		TargetUserId: targetUser.Id,
+	}, privilegeSnapshot(targetUser, privilege.PrivilegeType, privilege.Environment), nil)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	err = conn.Commit()
+	err = tx.Commit()
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
+	cache.PullCache.Invalidate(org.Name)
 	return gin.H{"code": 0, "msg": "OK"}, nil
 }
+
+// 删除组织下的所有项目, 组织自身的授权由调用方删除
+func deleteOrg(tx repo.Tx, orgId int) ([]model.Item, error) {
+	projects, err := tx.Projects().ListByParentId(orgId)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []model.Item
+	for _, project := range *projects {
+		items, err := deleteProject(tx, project.Id)
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, items...)
+	}
+	if err = tx.Orgs().Delete(orgId); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
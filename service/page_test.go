@@ -0,0 +1,116 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"reflect"
+	"testing"
+	"zoe/basic"
+	"zoe/model"
+)
+
+func pageNames(data gin.H) []string {
+	names := []string{}
+	for _, item := range data["items"].([]gin.H) {
+		names = append(names, item["name"].(string))
+	}
+	return names
+}
+
+func TestListProject(t *testing.T) {
+	f := newFixture(t)
+	// web是非公开的, 其余项目公开
+	for _, name := range []string{"api", "app", "cron", "docs"} {
+		mustOK(t)(CreateProject(f.alice, model.CreateProjectRequest{ParentId: f.org.Id, Name: name, Private: "false"}))
+	}
+	cases := []struct {
+		name      string
+		user      *model.User
+		query     model.ListQuery
+		wantTotal int
+		wantNames []string
+		wantErr   bool
+	}{
+		{"first page", f.alice, model.ListQuery{PageSize: 2}, 5, []string{"acme.web", "acme.api"}, false},
+		{"last page", f.alice, model.ListQuery{Page: 3, PageSize: 2}, 5, []string{"acme.docs"}, false},
+		{"past the end", f.alice, model.ListQuery{Page: 4, PageSize: 2}, 5, []string{}, false},
+		{"sort by name desc", f.alice, model.ListQuery{Sort: "-name", PageSize: 2}, 5, []string{"acme.web", "acme.docs"}, false},
+		{"search ignores org prefix", f.alice, model.ListQuery{Q: "ap", Sort: "name"}, 2, []string{"acme.api", "acme.app"}, false},
+		{"search is case insensitive", f.alice, model.ListQuery{Q: "CRON"}, 1, []string{"acme.cron"}, false},
+		{"private project hidden", f.bob, model.ListQuery{Sort: "name"}, 4, []string{"acme.api", "acme.app", "acme.cron", "acme.docs"}, false},
+		{"unknown sort field", f.alice, model.ListQuery{Sort: "visibility"}, 0, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ListProject(tc.user, f.org.Id, tc.query)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ListProject() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			data := mustOK(t)(result, err)
+			if data["total"] != tc.wantTotal {
+				t.Errorf("total = %v, want %d", data["total"], tc.wantTotal)
+			}
+			if got := pageNames(data); !reflect.DeepEqual(got, tc.wantNames) {
+				t.Errorf("names = %v, want %v", got, tc.wantNames)
+			}
+		})
+	}
+}
+
+func TestSingleOrgPage(t *testing.T) {
+	f := newFixture(t)
+	for _, name := range []string{"api", "app", "cron"} {
+		mustOK(t)(CreateProject(f.alice, model.CreateProjectRequest{ParentId: f.org.Id, Name: name, Private: "false"}))
+	}
+	mustOK(t)(AuthorizeOrg(f.alice, f.org.Id, model.AuthorizeOrgRequest{Type: "viewer", UserId: f.bob.Id}))
+
+	data := mustOK(t)(SingleOrg(f.alice, f.org.Id, model.ListQuery{Page: 2, PageSize: 3}))
+	if data["project_total"] != 4 || len(data["projects"].([]gin.H)) != 1 {
+		t.Errorf("projects = %v of %v, want 1 of 4", data["projects"], data["project_total"])
+	}
+	if data["privilege_total"] != 2 {
+		t.Errorf("privilege_total = %v, want 2", data["privilege_total"])
+	}
+	data = mustOK(t)(SingleOrg(f.bob, f.org.Id, model.ListQuery{PageSize: basic.MAX_PAGE_SIZE + 1}))
+	if data["page_size"] != basic.MAX_PAGE_SIZE || data["project_total"] != 4 {
+		t.Errorf("page_size = %v, project_total = %v, want %d and 4", data["page_size"], data["project_total"], basic.MAX_PAGE_SIZE)
+	}
+	if _, ok := data["privileges"]; ok {
+		t.Error("SingleOrg() should not show privileges to a viewer")
+	}
+}
+
+func TestListOrg(t *testing.T) {
+	setupStore(t)
+	alice := newUser(t, "alice")
+	bob := newUser(t, "bob")
+	for _, name := range []string{"acme", "beta", "core"} {
+		mustOK(t)(CreateOrg(alice, model.OrgCreateRequest{Name: name, Private: true}))
+	}
+	mustOK(t)(CreateOrg(bob, model.OrgCreateRequest{Name: "bobs"}))
+	cases := []struct {
+		name      string
+		user      *model.User
+		query     model.ListQuery
+		wantTotal int
+		wantNames []string
+	}{
+		{"own orgs", alice, model.ListQuery{}, 3, []string{"acme", "beta", "core"}},
+		{"paged desc", alice, model.ListQuery{Sort: "-name", Page: 2, PageSize: 2}, 3, []string{"acme"}},
+		{"search", alice, model.ListQuery{Q: "c"}, 2, []string{"acme", "core"}},
+		{"other user", bob, model.ListQuery{}, 1, []string{"bobs"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := mustOK(t)(ListOrg(tc.user, tc.query))
+			if data["total"] != tc.wantTotal {
+				t.Errorf("total = %v, want %d", data["total"], tc.wantTotal)
+			}
+			if got := pageNames(data); !reflect.DeepEqual(got, tc.wantNames) {
+				t.Errorf("names = %v, want %v", got, tc.wantNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/utils"
+)
+
+// 资源上的授权列表, 按用户名搜索, 可按id、user_name、type或created_at排序
+func listPrivilegePage(tx repo.Tx, resId, resType int, query model.ListQuery) (gin.H, error) {
+	field, desc, err := utils.ParseSort(query.Sort, "id", "user_name", "type", "created_at")
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize := utils.NormalizePage(query.Page, query.PageSize)
+	privileges, total, err := tx.Privileges().ListPageByResource(resId, resType,
+		repo.PageQuery{Name: query.Q, Sort: field, Desc: desc}, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	userIds := make([]int, len(*privileges))
+	for index, privilege := range *privileges {
+		userIds[index] = privilege.UserId
+	}
+	users, err := tx.Users().ListByIds(userIds)
+	if err != nil {
+		return nil, err
+	}
+	return utils.GetPageInfo(total, page, pageSize, utils.GetPrivilegeUserInfo(privileges, users)), nil
+}
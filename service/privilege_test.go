@@ -0,0 +1,132 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"testing"
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/model"
+)
+
+func TestResolvePrivilegeEnvironment(t *testing.T) {
+	f := newFixture(t)
+	carol := newUser(t, "carol")
+	prodItem := f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=prod", Environment: "prod"})
+	devItem := f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=dev", Environment: "dev"})
+	defaultItem := f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=default"})
+	mustOK(t)(AuthorizeProject(f.alice, f.project.Id, model.AuthorizeProjectRequest{Type: "modifier", UserId: f.bob.Id, Environment: "prod"}))
+	mustOK(t)(AuthorizeProject(f.alice, f.project.Id, model.AuthorizeProjectRequest{Type: "viewer", UserId: f.bob.Id, Environment: "dev"}))
+	mustOK(t)(AuthorizeOrg(f.alice, f.org.Id, model.AuthorizeOrgRequest{Type: "viewer", UserId: carol.Id, Environment: "prod"}))
+
+	cases := []struct {
+		name        string
+		user        *model.User
+		resId       int
+		resType     int
+		environment string
+		want        int
+	}{
+		{"project grant on its environment", f.bob, prodItem.Id, basic.Resource_Type_ITEM, "", basic.Privilege_Type_MODIFIER},
+		{"second grant on another environment", f.bob, devItem.Id, basic.Resource_Type_ITEM, "", basic.Privilege_Type_VIEWER},
+		{"no grant on default environment", f.bob, defaultItem.Id, basic.Resource_Type_ITEM, "", basic.Privilege_Type_NONE},
+		{"item ignores requested environment", f.bob, defaultItem.Id, basic.Resource_Type_ITEM, "prod", basic.Privilege_Type_NONE},
+		{"project without environment", f.bob, f.project.Id, basic.Resource_Type_PROJECT, "", basic.Privilege_Type_NONE},
+		{"project in prod", f.bob, f.project.Id, basic.Resource_Type_PROJECT, "prod", basic.Privilege_Type_MODIFIER},
+		{"project in dev", f.bob, f.project.Id, basic.Resource_Type_PROJECT, "dev", basic.Privilege_Type_VIEWER},
+		{"org grant reaches item", carol, prodItem.Id, basic.Resource_Type_ITEM, "", basic.Privilege_Type_VIEWER},
+		{"org grant stays in its environment", carol, devItem.Id, basic.Resource_Type_ITEM, "", basic.Privilege_Type_NONE},
+		{"org grant reaches project in prod", carol, f.project.Id, basic.Resource_Type_PROJECT, "prod", basic.Privilege_Type_VIEWER},
+		{"unscoped grant", f.alice, devItem.Id, basic.Resource_Type_ITEM, "", basic.Privilege_Type_MODIFIER},
+		{"anonymous", nil, f.project.Id, basic.Resource_Type_PROJECT, "", basic.Privilege_Type_NONE},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got int
+			view(t, func(tx repo.Tx) (err error) {
+				got, err = repo.ResolvePrivilegeInEnvironment(tx, tc.user, tc.resId, tc.resType, tc.environment)
+				return
+			})
+			if got != tc.want {
+				t.Errorf("ResolvePrivilegeInEnvironment() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEnvironmentScopedGrantAccess(t *testing.T) {
+	f := newFixture(t)
+	f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=prod", Environment: "prod"})
+	f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=dev", Environment: "dev"})
+	mustOK(t)(AuthorizeProject(f.alice, f.project.Id, model.AuthorizeProjectRequest{Type: "modifier", UserId: f.bob.Id, Environment: "prod"}))
+
+	for _, tc := range []struct {
+		environment string
+		wantErr     bool
+	}{
+		{"prod", false},
+		{"dev", true},
+		{"", true},
+	} {
+		_, err := CreateItem(f.bob, model.CreateItemRequest{ParentId: f.project.Id, Name: "cache", Environment: tc.environment})
+		if (err != nil) != tc.wantErr {
+			t.Errorf("CreateItem() in %q error = %v, wantErr %v", tc.environment, err, tc.wantErr)
+		}
+	}
+
+	data := mustOK(t)(SingleProject(f.bob, f.project.Id))
+	for _, item := range data["items"].([]gin.H) {
+		if item["environment"] != "prod" {
+			t.Errorf("SingleProject() returned item in %v", item["environment"])
+		}
+	}
+	if total := mustOK(t)(ListProject(f.bob, f.org.Id, model.ListQuery{}))["total"]; total != 1 {
+		t.Errorf("ListProject() total = %v, want 1", total)
+	}
+	mustOK(t)(ListEnvironment(f.bob, f.project.Id))
+
+	if _, err := DeleteAuthorizeProject(f.alice, f.project.Id, f.bob.Id, ""); err == nil {
+		t.Error("DeleteAuthorizeProject() without environment should not remove the prod grant")
+	}
+	mustOK(t)(DeleteAuthorizeProject(f.alice, f.project.Id, f.bob.Id, "prod"))
+	if _, err := SingleProject(f.bob, f.project.Id); err == nil {
+		t.Error("SingleProject() should fail after the grant is removed")
+	}
+	if total := mustOK(t)(ListProject(f.bob, f.org.Id, model.ListQuery{}))["total"]; total != 0 {
+		t.Errorf("ListProject() total = %v, want 0", total)
+	}
+}
+
+func TestAuthorizeProjectPerEnvironment(t *testing.T) {
+	f := newFixture(t)
+	grant := func(priType, environment string) {
+		t.Helper()
+		mustOK(t)(AuthorizeProject(f.alice, f.project.Id,
+			model.AuthorizeProjectRequest{Type: priType, UserId: f.bob.Id, Environment: environment}))
+	}
+	grant("modifier", "prod")
+	grant("viewer", "dev")
+	grant("viewer", "prod")
+	bobGrants := func() map[string]int {
+		grants := make(map[string]int)
+		view(t, func(tx repo.Tx) error {
+			privileges, err := tx.Privileges().ListByResource(f.project.Id, basic.Resource_Type_PROJECT)
+			if err != nil {
+				return err
+			}
+			for _, privilege := range *privileges {
+				if privilege.UserId == f.bob.Id {
+					grants[privilege.Environment] = privilege.PrivilegeType
+				}
+			}
+			return nil
+		})
+		return grants
+	}
+	if got := bobGrants(); len(got) != 2 || got["prod"] != basic.Privilege_Type_VIEWER || got["dev"] != basic.Privilege_Type_VIEWER {
+		t.Errorf("grants = %v, want viewer on prod and dev", got)
+	}
+	mustOK(t)(DeleteEnvironment(f.alice, f.project.Id, "dev"))
+	if got := bobGrants(); len(got) != 1 || got["prod"] != basic.Privilege_Type_VIEWER {
+		t.Errorf("grants after deleting dev = %v, want viewer on prod", got)
+	}
+}
@@ -3,52 +3,72 @@ package service
 import (
 	"errors"
 	"github.com/gin-gonic/gin"
+	"strings"
 	"zoe/basic"
-	"zoe/dao/db"
+	"zoe/cache"
+	"zoe/dao/repo"
 	"zoe/model"
 	"zoe/utils"
 )
 
-func CreateProject(userHash string, req model.CreateProjectRequest) (gin.H, error) {
-	conn, err := db.DB.Begin()
+func CreateProject(user *model.User, req model.CreateProjectRequest) (gin.H, error) {
+	tx, err := repo.DB.Begin()
 	if err != nil {
 		return nil, err
 	}
-	user, err := utils.GetUser(conn, userHash)
+	org, err := tx.Orgs().GetById(req.ParentId)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	org, err := db.QueryOrgById(conn, req.ParentId)
-	if err != nil {
-		_ = conn.Rollback()
-		return nil, err
+	if org == nil {
+		_ = tx.Rollback()
+		return nil, errors.New("不存在的组织")
 	}
-	flag, err := db.ValidateUserForProjectCreation(conn, user.UserHash, req.ParentId)
+	flag, err := repo.ValidateUserForProjectCreation(tx, user, req.ParentId)
 	if err != nil || !flag {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("用户无权限创建project")
 	}
-	var visibility int
-	if req.Private == "true" {
-		visibility = 0
-	} else if req.Private == "false" {
-		visibility = 1
+	if strings.Contains(req.Name, ".") {
+		_ = tx.Rollback()
+		return nil, errors.New("project名不能包含.")
 	}
-	id, err := db.CreateProject(conn, org.Name+"."+req.Name, visibility, req.ParentId)
+	name := org.Name + "." + req.Name
+	existing, err := tx.Projects().GetByParentIdAndName(org.Id, name)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if existing != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("该project已经存在")
+	}
+	visibility := utils.ParseVisibility(req.Private)
+	id, err := tx.Projects().Create(name, visibility, req.ParentId)
+	if err != nil {
+		_ = tx.Rollback()
 		return nil, errors.New("用户创建project失败")
 	}
-	err = db.AddWithCheck(conn, user.UserHash, req.Name, id,
+	if _, err = createVersion(tx, user.Id, id, basic.Resource_Type_PROJECT, name, "", visibility, 0); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = repo.AddWithCheck(tx, user.UserHash, name, id,
 		basic.Resource_Type_PROJECT, user.Id, basic.Privilege_Type_MODIFIER, visibility)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
+		return nil, err
+	}
+	project := &model.Project{Id: id, Name: name, ParentId: org.Id, Visibility: visibility}
+	err = recordProjectAudit(tx, user, basic.Audit_Action_CREATE, project, 0, nil, projectSnapshot(project))
+	if err != nil {
+		_ = tx.Rollback()
 		return nil, err
 	}
-	err = conn.Commit()
+	err = tx.Commit()
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
 	var visibilityStr string
@@ -69,38 +89,427 @@ func CreateProject(userHash string, req model.CreateProjectRequest) (gin.H, erro
 	}, nil
 }
 
-func UpdateProject(userHash string, projectId int, req model.UpdateProjectRequest) (gin.H, error) {
-	conn, err := db.DB.Begin()
+func UpdateProject(user *model.User, projectId int, req model.UpdateProjectRequest) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	project, err := tx.Projects().GetById(projectId)
+	if project == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标项目不存在")
+	}
+	flag, err := repo.ValidateForUserModifyProject(tx, user, projectId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限修改项目")
+	}
+	err = tx.Projects().UpdateVisibility(projectId, utils.ParseVisibility(req.Private))
 	if err != nil {
+		_ = tx.Rollback()
 		return nil, err
 	}
-	user, err := utils.GetUser(conn, userHash)
+	before := project
+	project, err = tx.Projects().GetById(projectId)
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	project, err := db.GetProjectById(conn, projectId)
+	if _, err = createVersion(tx, user.Id, project.Id, basic.Resource_Type_PROJECT, project.Name, "", project.Visibility, 0); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = recordProjectAudit(tx, user, basic.Audit_Action_UPDATE, project, 0, projectSnapshot(before), projectSnapshot(project))
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(project.Name)
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+	}, nil
+}
+
+func listVisibleItem(tx repo.Tx, user *model.User, project *model.Project) (*[]model.Item, error) {
+	items, err := tx.Items().ListByParentId(project.Id)
+	if err != nil {
+		return nil, err
+	}
+	flag, err := repo.ValidateForUserViewProject(tx, user, project.Id)
+	if err != nil {
+		return nil, err
+	}
+	if flag {
+		return items, nil
+	}
+	var visibleItems []model.Item
+	for _, item := range *items {
+		if item.Visibility == 1 {
+			visibleItems = append(visibleItems, item)
+			continue
+		}
+		flag, err := repo.ValidateForUserViewItem(tx, user, item.Id)
+		if err != nil {
+			return nil, err
+		}
+		if flag {
+			visibleItems = append(visibleItems, item)
+		}
+	}
+	return &visibleItems, nil
+}
+
+func SingleProject(user *model.User, projectId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	project, err := tx.Projects().GetById(projectId)
 	if project == nil || err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, errors.New("目标项目不存在")
 	}
-	flag, err := db.ValidateForUserModifyProject(conn, user.UserHash, projectId, project.ParentId)
+	// 只能查看部分环境的用户也能看到项目, 其中的item由listVisibleItem按环境过滤
+	flag, err := repo.ValidateForUserViewAnyEnvironment(tx, user, project.Id, basic.Resource_Type_PROJECT)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if !flag && project.Visibility != 1 {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限查看该项目")
+	}
+	items, err := listVisibleItem(tx, user, project)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	projectInfo := utils.GetProjectInfo(&[]model.Project{*project})[0]
+	projectInfo["items"] = utils.GetItemsInfo(items)
+	flag, err = repo.ValidateForUserModifyProject(tx, user, project.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if flag {
+		privileges, err := tx.Privileges().ListByResource(project.Id, basic.Resource_Type_PROJECT)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		userIds := make([]int, len(*privileges))
+		for index, privilege := range *privileges {
+			userIds[index] = privilege.UserId
+		}
+		users, err := tx.Users().ListByIds(userIds)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		projectInfo["privileges"] = utils.GetPrivilegeUserInfo(privileges, users)
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": projectInfo,
+	}, nil
+}
+
+// 不能查看整个组织时, 返回用户能查看的非公开项目; 能查看整个组织时返回nil.
+// 限定了环境的授权也使项目可见
+func listVisibleProjectIds(tx repo.Tx, user *model.User, orgId int) ([]int, error) {
+	flag, err := repo.ValidateForUserViewAnyEnvironment(tx, user, orgId, basic.Resource_Type_ORG)
+	if err != nil {
+		return nil, err
+	}
+	if flag {
+		return nil, nil
+	}
+	projectIds := []int{}
+	if user == nil {
+		return projectIds, nil
+	}
+	// 只可能通过项目上的授权, 或限定在项目上的token继承组织的授权查看
+	candidates := make(map[int]bool)
+	if user.Token != nil && user.Token.ResourceType == basic.Resource_Type_PROJECT {
+		candidates[user.Token.ResourceId] = true
+	}
+	privileges, err := tx.Privileges().ListByUserHash(user.UserHash)
+	if err != nil {
+		return nil, err
+	}
+	for _, privilege := range *privileges {
+		if privilege.ResourceType == basic.Resource_Type_PROJECT {
+			candidates[privilege.ResourceId] = true
+		}
+	}
+	for projectId := range candidates {
+		flag, err := repo.ValidateForUserViewAnyEnvironment(tx, user, projectId, basic.Resource_Type_PROJECT)
+		if err != nil {
+			return nil, err
+		}
+		if flag {
+			projectIds = append(projectIds, projectId)
+		}
+	}
+	return projectIds, nil
+}
+
+// 按项目名(不含组织前缀)搜索, 可按id、name或created_at排序
+func ListProject(user *model.User, orgId int, query model.ListQuery) (gin.H, error) {
+	field, desc, err := utils.ParseSort(query.Sort, "id", "name", "created_at")
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize := utils.NormalizePage(query.Page, query.PageSize)
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	org, err := tx.Orgs().GetById(orgId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if org == nil {
+		_ = tx.Rollback()
+		return nil, errors.New("不存在的组织")
+	}
+	visibleIds, err := listVisibleProjectIds(tx, user, orgId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	projects, total, err := tx.Projects().ListPageByParentId(orgId, visibleIds,
+		repo.PageQuery{NamePrefix: org.Name + ".", Name: query.Q, Sort: field, Desc: desc}, (page-1)*pageSize, pageSize)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": utils.GetPageInfo(total, page, pageSize, utils.GetProjectInfo(projects)),
+	}, nil
+}
+
+func DeleteProject(user *model.User, projectId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	project, err := tx.Projects().GetById(projectId)
+	if project == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标项目不存在")
+	}
+	flag, err := repo.ValidateForUserModifyProject(tx, user, projectId)
 	if err != nil || !flag {
-		_ = conn.Rollback()
-		return nil, errors.New("用户无权限修改项目")
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限删除项目")
+	}
+	items, err := deleteProject(tx, projectId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = recordProjectAudit(tx, user, basic.Audit_Action_DELETE, project, 0, projectSnapshot(project), nil)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(project.Name)
+	notifyItems(items)
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+	}, nil
+}
+
+func AuthorizeProject(user *model.User, projectId int, req model.AuthorizeProjectRequest) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	project, err := tx.Projects().GetById(projectId)
+	if project == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标项目不存在")
+	}
+	flag, err := repo.ValidateForUserModifyProject(tx, user, projectId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限修改该项目")
+	}
+	targetUser, err := tx.Users().GetById(req.UserId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标用户不存在")
+	}
+	if targetUser.Id == user.Id {
+		_ = tx.Rollback()
+		return nil, errors.New("你不能为自己授权")
+	}
+	priType, err := utils.ParsePrivilegeType(req.Type)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = checkEnvironment(tx, projectId, req.Environment); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	privilege, err := tx.Privileges().Get(targetUser.UserHash, req.Environment, projectId, basic.Resource_Type_PROJECT)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	var before interface{}
+	if privilege != nil {
+		before = privilegeSnapshot(targetUser, privilege.PrivilegeType, privilege.Environment)
+		err = tx.Privileges().Update(targetUser.UserHash, req.Environment, priType, projectId, basic.Resource_Type_PROJECT)
+	} else {
+		err = tx.Privileges().Create(targetUser.UserHash, project.Name, req.Environment, projectId, basic.Resource_Type_PROJECT,
+			targetUser.Id, priType, project.Visibility)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
 	}
-	err = db.UpdateProject(conn, projectId, req.Private)
+	err = recordProjectAudit(tx, user, basic.Audit_Action_AUTHORIZE, project, targetUser.Id,
+		before, privilegeSnapshot(targetUser, priType, req.Environment))
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
 		return nil, err
 	}
-	err = conn.Commit()
+	err = tx.Commit()
 	if err != nil {
-		_ = conn.Rollback()
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(project.Name)
+	return gin.H{"code": 0, "msg": "OK"}, nil
+}
+
+// environment指定要删除的是限定在哪个环境的授权, 为空时删除不限定环境的授权
+func DeleteAuthorizeProject(user *model.User, projectId, userId int, environment string) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	project, err := tx.Projects().GetById(projectId)
+	if project == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标项目不存在")
+	}
+	flag, err := repo.ValidateForUserModifyProject(tx, user, projectId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限修改该项目")
+	}
+	targetUser, err := tx.Users().GetById(userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标用户不存在")
+	}
+	privilege, err := tx.Privileges().Get(targetUser.UserHash, environment, projectId, basic.Resource_Type_PROJECT)
+	if err != nil || privilege == nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标用户无该项目的权限")
+	}
+	err = tx.Privileges().Delete(targetUser.UserHash, environment, projectId, basic.Resource_Type_PROJECT)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = recordProjectAudit(tx, user, basic.Audit_Action_DELETE_AUTHORIZE, project, targetUser.Id,
+		privilegeSnapshot(targetUser, privilege.PrivilegeType, privilege.Environment), nil)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	cache.PullCache.Invalidate(project.Name)
+	return gin.H{"code": 0, "msg": "OK"}, nil
+}
+
+func ListAuthorizeProject(user *model.User, projectId int, query model.ListQuery) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	project, err := tx.Projects().GetById(projectId)
+	if project == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标项目不存在")
+	}
+	flag, err := repo.ValidateForUserModifyProject(tx, user, projectId)
+	if err != nil || !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限查看该项目的授权")
+	}
+	data, err := listPrivilegePage(tx, projectId, basic.Resource_Type_PROJECT, query)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
 		return nil, err
 	}
 	return gin.H{
 		"code": 0,
 		"msg":  "OK",
+		"data": data,
 	}, nil
 }
+
+// 删除项目及其下的item, 同时清理相关的授权
+// 返回被级联删除的item, 供提交后逐个失效缓存并通知watch
+func deleteProject(tx repo.Tx, projectId int) ([]model.Item, error) {
+	items, err := tx.Items().ListByParentId(projectId)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range *items {
+		if err = deleteItem(tx, item.Id); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Privileges().DeleteByResource(projectId, basic.Resource_Type_PROJECT); err != nil {
+		return nil, err
+	}
+	if err = tx.Environments().DeleteByProjectId(projectId); err != nil {
+		return nil, err
+	}
+	if err = tx.Projects().Delete(projectId); err != nil {
+		return nil, err
+	}
+	return *items, nil
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+	"time"
+	"zoe/basic"
+	"zoe/cache"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/notify"
+	"zoe/utils"
+)
+
+var (
+	ErrPullNotFound  = errors.New("拉取的资源不存在")
+	ErrPullForbidden = errors.New("无权限拉取该资源")
+	ErrPullFormat    = errors.New("无法按请求的格式输出")
+)
+
+type pullGray struct {
+	VersionId int
+	Content   string
+	Rule      *utils.GrayRule
+}
+
+type pullResult struct {
+	Name        string
+	Environment string
+	ContentType string
+	Content     string
+	VersionId   int
+	Gray        *pullGray
+}
+
+// 命中灰度规则的客户端拿到灰度版本, 其余客户端拿到当前版本
+func (r *pullResult) resolve(clientId, clientIp string) (string, int, bool) {
+	if r.Gray != nil && r.Gray.Rule.Match(r.Name, clientId, clientIp) {
+		return r.Gray.Content, r.Gray.VersionId, true
+	}
+	return r.Content, r.VersionId, false
+}
+
+// 拉取结果的缓存标识: 匿名拉取时为空串, API token的范围不同权限也不同, 需要区分
+func getPullerHash(user *model.User) string {
+	if user == nil {
+		return ""
+	}
+	if user.Token != nil {
+		return user.UserHash + "#" + strconv.Itoa(user.Token.Id)
+	}
+	return user.UserHash
+}
+
+// 各环境的item互相独立, 不存在时不会回落到默认环境. fresh为true时跳过缓存直接读库, 并用结果刷新缓存
+func getPullResult(user *model.User, orgName, projectName, itemName, environment string, fresh bool) (*pullResult, error) {
+	resName := utils.GetItemKey(orgName+"."+projectName+"."+itemName, environment)
+	pullerHash := getPullerHash(user)
+	if !fresh {
+		if value, ok := cache.PullCache.Get(resName, pullerHash); ok {
+			return value.(*pullResult), nil
+		}
+	}
+	generation := cache.PullCache.Generation()
+	result, err := pullFromDB(user, orgName, projectName, itemName, environment)
+	if err != nil {
+		return nil, err
+	}
+	cache.PullCache.Set(resName, pullerHash, result, generation)
+	return result, nil
+}
+
+// format不为空时把内容转换为该格式输出, 如把yaml格式的item按json返回
+func Pull(user *model.User, clientId, clientIp, environment, format, orgName, projectName, itemName string) (gin.H, error) {
+	result, err := getPullResult(user, orgName, projectName, itemName, environment, false)
+	if err != nil {
+		return nil, err
+	}
+	content, versionId, gray := result.resolve(clientId, clientIp)
+	contentType := result.ContentType
+	if format != "" {
+		if content, err = utils.RenderContent(result.ContentType, content, format); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrPullFormat, err.Error())
+		}
+		contentType = format
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": gin.H{
+			"name":         result.Name,
+			"environment":  result.Environment,
+			"content_type": contentType,
+			"content":      content,
+			"version_id":   versionId,
+			"gray":         gray,
+		},
+	}, nil
+}
+
+func pullFromDB(user *model.User, orgName, projectName, itemName, environment string) (*pullResult, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	org, err := tx.Orgs().GetByName(orgName)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if org == nil {
+		_ = tx.Rollback()
+		return nil, ErrPullNotFound
+	}
+	project, err := tx.Projects().GetByParentIdAndName(org.Id, org.Name+"."+projectName)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if project == nil {
+		_ = tx.Rollback()
+		return nil, ErrPullNotFound
+	}
+	item, err := tx.Items().GetByParentIdAndName(project.Id, project.Name+"."+itemName, environment)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if item == nil {
+		_ = tx.Rollback()
+		return nil, ErrPullNotFound
+	}
+	// 组织、项目和item均公开时允许匿名拉取, 加密item始终需要拉取权限
+	public := org.Visibility == 1 && project.Visibility == 1 && item.Visibility == 1 && item.Encrypted == 0
+	if !public {
+		if user == nil {
+			_ = tx.Rollback()
+			return nil, ErrPullForbidden
+		}
+		validate := repo.ValidateForUserPullItem
+		if item.Encrypted == 1 {
+			validate = repo.ValidateForUserPullSecretItem
+		}
+		flag, err := validate(tx, user, item.Id)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if !flag {
+			_ = tx.Rollback()
+			return nil, ErrPullForbidden
+		}
+	}
+	content, err := openContent(item, item.Content)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	result := &pullResult{
+		Name:        item.Name,
+		Environment: item.Environment,
+		ContentType: item.ContentType,
+		Content:     content,
+		VersionId:   item.CurrentVersionId,
+	}
+	gray, err := tx.Grays().GetRunningByItemId(item.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if gray != nil {
+		version, err := tx.Versions().GetById(gray.VersionId)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		rule, err := utils.NewGrayRule(gray.ClientIds, gray.IpRanges, gray.Percentage)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if version != nil {
+			grayContent, err := openVersion(version)
+			if err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+			result.Gray = &pullGray{VersionId: version.Id, Content: grayContent, Rule: rule}
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return result, nil
+}
+
+func checkWatchItems(user *model.User, clientId, clientIp string, items []model.WatchItem, fresh bool) ([]gin.H, error) {
+	var changed []gin.H
+	for _, item := range items {
+		arr := strings.Split(item.Name, ".")
+		if len(arr) != 3 {
+			return nil, ErrPullNotFound
+		}
+		versionId := 0
+		result, err := getPullResult(user, arr[0], arr[1], arr[2], item.Environment, fresh)
+		if err == nil {
+			_, versionId, _ = result.resolve(clientId, clientIp)
+		} else if err != ErrPullNotFound {
+			return nil, err
+		}
+		if versionId != item.VersionId {
+			changed = append(changed, gin.H{"name": item.Name, "environment": item.Environment, "version_id": versionId})
+		}
+	}
+	return changed, nil
+}
+
+// 阻塞直到任一item的版本与客户端持有的不同, 或超时返回空列表.
+// 未指定环境的item使用请求级别的环境
+func Watch(ctx context.Context, user *model.User, clientId, clientIp, environment string, req model.WatchRequest) (gin.H, error) {
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = basic.DEFAULT_WATCH_TIMEOUT
+	} else if timeout > basic.MAX_WATCH_TIMEOUT {
+		timeout = basic.MAX_WATCH_TIMEOUT
+	}
+	names := make([]string, len(req.Items))
+	for index := range req.Items {
+		if req.Items[index].Environment == "" {
+			req.Items[index].Environment = environment
+		}
+		names[index] = utils.GetItemKey(req.Items[index].Name, req.Items[index].Environment)
+	}
+	// 先订阅再检查, 避免错过两者之间发生的变更
+	ch, cancel := notify.ItemHub.Subscribe(names)
+	defer cancel()
+	timer := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer timer.Stop()
+	// 定期复查, 兼顾其它实例上发生的变更; 其它实例的变更不会使本实例的缓存失效, 复查时直接读库
+	ticker := time.NewTicker(basic.WATCH_RECHECK_INTERVAL * time.Second)
+	defer ticker.Stop()
+	fresh := false
+	for {
+		changed, err := checkWatchItems(user, clientId, clientIp, req.Items, fresh)
+		if err != nil {
+			return nil, err
+		}
+		if len(changed) > 0 {
+			return gin.H{"code": 0, "msg": "OK", "data": changed}, nil
+		}
+		fresh = false
+		select {
+		case <-ch:
+		case <-ticker.C:
+			fresh = true
+		case <-timer.C:
+			return gin.H{"code": 0, "msg": "OK", "data": []gin.H{}}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"sync"
+	"time"
+	"zoe/basic"
+	"zoe/config"
+	"zoe/dao/repo"
+	"zoe/keyring"
+	"zoe/model"
+)
+
+// 密钥轮换某一阶段的进度, Total为该阶段需要检查的密文总数
+type RotateProgress struct {
+	Stage   string
+	Total   int
+	Scanned int
+	Rotated int
+}
+
+// 管理接口发起的轮换在后台执行, 同一时间只允许一个
+type keyRotation struct {
+	From       string
+	To         string
+	Status     string
+	Error      string
+	Progress   map[string]RotateProgress
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+var rotation struct {
+	sync.Mutex
+	current *keyRotation
+}
+
+// 管理员由配置指定, 受限的API token不能执行管理操作
+func isAdmin(user *model.User) bool {
+	if user == nil || (user.Token != nil && (user.Token.ReadOnly == 1 || user.Token.ResourceId != 0)) {
+		return false
+	}
+	for _, name := range config.C.Admins {
+		if name == user.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func checkRotateKeys(from, to string) error {
+	if from == to {
+		return errors.New("新旧密钥不能相同")
+	}
+	if !keyring.Keys.HasKey(from) {
+		return fmt.Errorf("密钥%s不存在", from)
+	}
+	if !keyring.Keys.HasKey(to) {
+		return fmt.Errorf("密钥%s不存在", to)
+	}
+	return nil
+}
+
+// 只改写使用from密钥的密文, 其余内容原样返回
+func rotateContent(content, from, to string) (string, bool, error) {
+	if keyring.KeyIdOf(content) != from {
+		return content, false, nil
+	}
+	plaintext, err := keyring.Keys.Decrypt(content)
+	if err != nil {
+		return "", false, err
+	}
+	ciphertext, err := keyring.Keys.EncryptWith(to, plaintext)
+	if err != nil {
+		return "", false, err
+	}
+	return ciphertext, true, nil
+}
+
+func rotateItemBatch(from, to string, afterId, batchSize int, progress *RotateProgress) (int, bool, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	items, total, err := tx.Items().ListEncrypted(afterId, batchSize)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, false, err
+	}
+	rotated := 0
+	for _, item := range *items {
+		content, changed, err := rotateContent(item.Content, from, to)
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, false, fmt.Errorf("item %d: %s", item.Id, err.Error())
+		}
+		if changed {
+			if changed, err = tx.Items().ReplaceContent(item.Id, item.Content, content); err != nil {
+				_ = tx.Rollback()
+				return 0, false, err
+			}
+			if changed {
+				rotated++
+			}
+		}
+		draft, err := tx.Drafts().GetByItemId(item.Id)
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, false, err
+		}
+		if draft == nil {
+			continue
+		}
+		if content, changed, err = rotateContent(draft.Content, from, to); err != nil {
+			_ = tx.Rollback()
+			return 0, false, fmt.Errorf("item %d的草稿: %s", item.Id, err.Error())
+		}
+		if changed {
+			if _, err = tx.Drafts().ReplaceContent(item.Id, draft.Content, content); err != nil {
+				_ = tx.Rollback()
+				return 0, false, err
+			}
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, false, err
+	}
+	if len(*items) == 0 {
+		return afterId, true, nil
+	}
+	progress.Total = total
+	progress.Scanned += len(*items)
+	progress.Rotated += rotated
+	return (*items)[len(*items)-1].Id, false, nil
+}
+
+func rotateVersionBatch(from, to string, afterId, batchSize int, progress *RotateProgress) (int, bool, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	versions, total, err := tx.Versions().ListEncrypted(afterId, batchSize)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, false, err
+	}
+	rotated := 0
+	for _, version := range *versions {
+		content, changed, err := rotateContent(version.Content, from, to)
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, false, fmt.Errorf("版本%d: %s", version.Id, err.Error())
+		}
+		if !changed {
+			continue
+		}
+		if changed, err = tx.Versions().ReplaceContent(version.Id, version.Content, content); err != nil {
+			_ = tx.Rollback()
+			return 0, false, err
+		}
+		if changed {
+			rotated++
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, false, err
+	}
+	if len(*versions) == 0 {
+		return afterId, true, nil
+	}
+	progress.Total = total
+	progress.Scanned += len(*versions)
+	progress.Rotated += rotated
+	return (*versions)[len(*versions)-1].Id, false, nil
+}
+
+// 把item、草稿和全部历史版本中使用from密钥的密文改写为to密钥.
+// 每批在单独的事务中提交, 不会长时间阻塞读写; 中断后重新执行会跳过已改写的内容.
+// 轮换前需先把to设为配置中的当前密钥, 否则轮换期间新写入的内容仍会使用from密钥
+func RotateKey(from, to string, batchSize int, progress func(RotateProgress)) error {
+	if err := checkRotateKeys(from, to); err != nil {
+		return err
+	}
+	if batchSize <= 0 {
+		batchSize = basic.DEFAULT_ROTATE_BATCH_SIZE
+	} else if batchSize > basic.MAX_ROTATE_BATCH_SIZE {
+		batchSize = basic.MAX_ROTATE_BATCH_SIZE
+	}
+	stages := []struct {
+		name  string
+		batch func(from, to string, afterId, batchSize int, progress *RotateProgress) (int, bool, error)
+	}{
+		{basic.Rotate_Stage_ITEM, rotateItemBatch},
+		{basic.Rotate_Stage_VERSION, rotateVersionBatch},
+	}
+	for _, stage := range stages {
+		current := RotateProgress{Stage: stage.name}
+		afterId := 0
+		for {
+			var done bool
+			var err error
+			if afterId, done, err = stage.batch(from, to, afterId, batchSize, &current); err != nil {
+				return err
+			}
+			if done {
+				break
+			}
+			if progress != nil {
+				progress(current)
+			}
+		}
+		// 没有需要检查的内容时也报告一次, 便于确认该阶段已完成
+		if current.Scanned == 0 && progress != nil {
+			progress(current)
+		}
+	}
+	return nil
+}
+
+func getKeyRotationInfo(r *keyRotation) gin.H {
+	if r == nil {
+		return nil
+	}
+	progress := gin.H{}
+	for stage, p := range r.Progress {
+		progress[stage] = gin.H{"total": p.Total, "scanned": p.Scanned, "rotated": p.Rotated}
+	}
+	return gin.H{
+		"from":        r.From,
+		"to":          r.To,
+		"status":      r.Status,
+		"error":       r.Error,
+		"progress":    progress,
+		"started_at":  r.StartedAt,
+		"finished_at": r.FinishedAt,
+	}
+}
+
+func StartKeyRotation(user *model.User, req model.RotateKeyRequest) (gin.H, error) {
+	if !isAdmin(user) {
+		return nil, errors.New("只有管理员可以轮换密钥")
+	}
+	to := req.To
+	if to == "" {
+		to = keyring.Keys.KeyId()
+	}
+	if err := checkRotateKeys(req.From, to); err != nil {
+		return nil, err
+	}
+	rotation.Lock()
+	defer rotation.Unlock()
+	if rotation.current != nil && rotation.current.Status == basic.Rotate_Status_RUNNING {
+		return nil, errors.New("已有进行中的密钥轮换")
+	}
+	r := &keyRotation{From: req.From, To: to, Status: basic.Rotate_Status_RUNNING,
+		Progress: map[string]RotateProgress{}, StartedAt: time.Now()}
+	rotation.current = r
+	go func() {
+		err := RotateKey(r.From, r.To, req.BatchSize, func(p RotateProgress) {
+			rotation.Lock()
+			r.Progress[p.Stage] = p
+			rotation.Unlock()
+		})
+		rotation.Lock()
+		defer rotation.Unlock()
+		now := time.Now()
+		r.FinishedAt = &now
+		if err != nil {
+			r.Status = basic.Rotate_Status_FAILED
+			r.Error = err.Error()
+		} else {
+			r.Status = basic.Rotate_Status_DONE
+		}
+	}()
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": getKeyRotationInfo(r),
+	}, nil
+}
+
+// 返回最近一次由管理接口发起的轮换, 进程重启后不保留
+func GetKeyRotation(user *model.User) (gin.H, error) {
+	if !isAdmin(user) {
+		return nil, errors.New("只有管理员可以查看密钥轮换")
+	}
+	rotation.Lock()
+	defer rotation.Unlock()
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": getKeyRotationInfo(rotation.current),
+	}, nil
+}
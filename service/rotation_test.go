@@ -0,0 +1,115 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"zoe/basic"
+	"zoe/config"
+	"zoe/dao/repo"
+	"zoe/keyring"
+	"zoe/model"
+)
+
+// item当前内容、草稿和历史版本使用的密钥
+func contentKeyIds(t *testing.T, itemId int) []string {
+	t.Helper()
+	var keyIds []string
+	view(t, func(tx repo.Tx) error {
+		item, err := tx.Items().GetById(itemId)
+		if err != nil {
+			return err
+		}
+		keyIds = append(keyIds, keyring.KeyIdOf(item.Content))
+		draft, err := tx.Drafts().GetByItemId(itemId)
+		if err != nil {
+			return err
+		}
+		if draft != nil {
+			keyIds = append(keyIds, keyring.KeyIdOf(draft.Content))
+		}
+		versions, err := tx.Versions().ListByResource(itemId, basic.Resource_Type_ITEM)
+		if err != nil {
+			return err
+		}
+		for _, version := range *versions {
+			keyIds = append(keyIds, keyring.KeyIdOf(version.Content))
+		}
+		return nil
+	})
+	return keyIds
+}
+
+func TestRotateKey(t *testing.T) {
+	f := newFixture(t)
+	item := f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=1", Encrypted: true})
+	mustOK(t)(SaveDraft(f.alice, item.Id, model.SaveDraftRequest{Content: "a=2"}))
+	mustOK(t)(PublishItem(f.alice, item.Id))
+	mustOK(t)(SaveDraft(f.alice, item.Id, model.SaveDraftRequest{Content: "a=3"}))
+	plain := f.createItem(t, model.CreateItemRequest{Name: "plain", Content: "b=1"})
+
+	keyring.Keys = newTestKeyring(t, "k2", testKeys)
+	for _, tc := range []struct{ from, to string }{{"k1", "k1"}, {"k3", "k2"}, {"k1", "k3"}} {
+		if err := RotateKey(tc.from, tc.to, 1, nil); err == nil {
+			t.Errorf("RotateKey(%s, %s) should fail", tc.from, tc.to)
+		}
+	}
+
+	rotated := make(map[string]int)
+	progress := func(p RotateProgress) { rotated[p.Stage] = p.Rotated }
+	if err := RotateKey("k1", "k2", 1, progress); err != nil {
+		t.Fatalf("RotateKey() error = %v", err)
+	}
+	if rotated[basic.Rotate_Stage_ITEM] != 1 || rotated[basic.Rotate_Stage_VERSION] != 2 {
+		t.Errorf("rotated = %v, want 1 item and 2 versions", rotated)
+	}
+	for _, keyId := range contentKeyIds(t, item.Id) {
+		if keyId != "k2" {
+			t.Errorf("content still uses key %q", keyId)
+		}
+	}
+	for _, keyId := range contentKeyIds(t, plain.Id) {
+		if keyId != "" {
+			t.Errorf("plain content was encrypted with %q", keyId)
+		}
+	}
+	if got := f.pull(t, f.alice, "db", "")["content"]; got != "a=2" {
+		t.Errorf("Pull() content = %v, want a=2", got)
+	}
+
+	// 重复执行时跳过已改写的内容
+	rotated = make(map[string]int)
+	if err := RotateKey("k1", "k2", 0, progress); err != nil {
+		t.Fatalf("RotateKey() again error = %v", err)
+	}
+	if rotated[basic.Rotate_Stage_ITEM] != 0 || rotated[basic.Rotate_Stage_VERSION] != 0 {
+		t.Errorf("rotated again = %v, want nothing", rotated)
+	}
+}
+
+func TestStartKeyRotation(t *testing.T) {
+	f := newFixture(t)
+	f.createItem(t, model.CreateItemRequest{Name: "db", Content: "a=1", Encrypted: true})
+	keyring.Keys = newTestKeyring(t, "k2", testKeys)
+	config.C.Admins = []string{"alice"}
+	tokenUser := newTokenUser(t, f.alice, model.CreateTokenRequest{Name: "ro", ReadOnly: true})
+	for _, user := range []*model.User{f.bob, tokenUser} {
+		if _, err := StartKeyRotation(user, model.RotateKeyRequest{From: "k1"}); err == nil {
+			t.Errorf("StartKeyRotation() by %s should fail", user.Name)
+		}
+	}
+	data := mustOK(t)(StartKeyRotation(f.alice, model.RotateKeyRequest{From: "k1"}))
+	if data["to"] != "k2" {
+		t.Errorf("to = %v, want the current key k2", data["to"])
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data = mustOK(t)(GetKeyRotation(f.alice))
+		if data["status"] != basic.Rotate_Status_RUNNING || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if data["status"] != basic.Rotate_Status_DONE {
+		t.Errorf("status = %v, error = %v, want %s", data["status"], data["error"], basic.Rotate_Status_DONE)
+	}
+}
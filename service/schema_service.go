@@ -0,0 +1,206 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/utils"
+)
+
+func getSchemaInfo(schema *model.Schema) gin.H {
+	var content interface{}
+	if schema.Content != "" {
+		content = json.RawMessage(schema.Content)
+	}
+	return gin.H{
+		"id":         schema.Id,
+		"version":    schema.Version,
+		"schema":     content,
+		"user_id":    schema.UserId,
+		"created_at": schema.CreateAt,
+	}
+}
+
+func schemaSnapshot(schema *model.Schema) interface{} {
+	if schema == nil {
+		return nil
+	}
+	return gin.H{"version": schema.Version, "schema": schema.Content}
+}
+
+// 发布前按所属project和item自身的schema校验内容, 两者都有时都要满足. itemId为0时只校验project的schema
+func checkContentSchema(tx repo.Tx, projectId, itemId int, contentType, content string) error {
+	if contentType == basic.Content_Type_TEXT {
+		return nil
+	}
+	resIds := []int{projectId, itemId}
+	resTypes := []int{basic.Resource_Type_PROJECT, basic.Resource_Type_ITEM}
+	var errs []error
+	for index := range resIds {
+		if resIds[index] == 0 {
+			continue
+		}
+		schema, err := tx.Schemas().GetLatest(resIds[index], resTypes[index])
+		if err != nil {
+			return err
+		}
+		if schema == nil || schema.Content == "" {
+			continue
+		}
+		errs = append(errs, utils.CheckSchema(schema.Content, utils.FormatResourceType(resTypes[index]), contentType, content))
+	}
+	return utils.MergeSchemaErrors(errs...)
+}
+
+// 与当前版本相同时不追加新版本, 返回的bool表示是否有修改
+func setSchema(tx repo.Tx, userId, resId, resType int, content string) (*model.Schema, *model.Schema, bool, error) {
+	latest, err := tx.Schemas().GetLatest(resId, resType)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if (latest == nil && content == "") || (latest != nil && latest.Content == content) {
+		return latest, latest, false, nil
+	}
+	version := 1
+	if latest != nil {
+		version = latest.Version + 1
+	}
+	if _, err = tx.Schemas().Create(resId, resType, version, content, userId); err != nil {
+		return nil, nil, false, err
+	}
+	schema, err := tx.Schemas().GetLatest(resId, resType)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if latest != nil && latest.Content == "" {
+		latest = nil
+	}
+	return latest, schema, true, nil
+}
+
+// schema统一压缩后保存, 避免仅格式不同的内容产生新版本
+func normalizeSchema(schema string) (string, error) {
+	if schema == "" {
+		return "", nil
+	}
+	if err := utils.CompileSchema(schema); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(schema)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// schema为空时移除当前的schema, 历史版本仍然保留
+func SetSchema(user *model.User, resId, resType int, schema string) (gin.H, error) {
+	content, err := normalizeSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	var project *model.Project
+	var item *model.Item
+	switch resType {
+	case basic.Resource_Type_PROJECT:
+		project, err = tx.Projects().GetById(resId)
+		if project == nil || err != nil {
+			_ = tx.Rollback()
+			return nil, errors.New("目标项目不存在")
+		}
+		flag, err := repo.ValidateForUserModifyProject(tx, user, resId)
+		if err != nil || !flag {
+			_ = tx.Rollback()
+			return nil, errors.New("用户无权限修改该项目")
+		}
+	case basic.Resource_Type_ITEM:
+		if item, err = getItemForModify(tx, user, resId); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if content != "" && item.ContentType == basic.Content_Type_TEXT {
+			_ = tx.Rollback()
+			return nil, errors.New("纯文本格式的item不能设置schema")
+		}
+	default:
+		_ = tx.Rollback()
+		return nil, errors.New("非法的资源类型")
+	}
+	before, after, changed, err := setSchema(tx, user.Id, resId, resType, content)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if changed {
+		if project != nil {
+			err = recordProjectAudit(tx, user, basic.Audit_Action_SET_SCHEMA, project, 0, schemaSnapshot(before), schemaSnapshot(after))
+		} else {
+			err = recordItemAudit(tx, user, basic.Audit_Action_SET_SCHEMA, item, 0, schemaSnapshot(before), schemaSnapshot(after))
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	var data interface{}
+	if after != nil {
+		data = getSchemaInfo(after)
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": data,
+	}, nil
+}
+
+// 按版本倒序返回全部历史, 第一个即当前版本
+func ListSchema(user *model.User, resId, resType int) (gin.H, error) {
+	if resType != basic.Resource_Type_PROJECT && resType != basic.Resource_Type_ITEM {
+		return nil, errors.New("非法的资源类型")
+	}
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	flag, err := validateForUserViewResource(tx, user, resId, resType)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限查看该资源")
+	}
+	schemas, err := tx.Schemas().ListByResource(resId, resType)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	data := make([]gin.H, 0, len(*schemas))
+	for index := range *schemas {
+		data = append(data, getSchemaInfo(&(*schemas)[index]))
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": data,
+	}, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"testing"
+	"zoe/basic"
+	"zoe/model"
+)
+
+const portSchema = `{"type": "object", "required": ["port"], "properties": {"port": {"type": "integer"}}}`
+
+func TestProjectSchema(t *testing.T) {
+	f := newFixture(t)
+	mustOK(t)(SetSchema(f.alice, f.project.Id, basic.Resource_Type_PROJECT, portSchema))
+	cases := []struct {
+		name        string
+		contentType string
+		content     string
+		wantErr     bool
+	}{
+		{"valid json", "json", `{"port": 80}`, false},
+		{"wrong type", "json", `{"port": "80"}`, true},
+		{"missing field", "json", `{}`, true},
+		{"valid yaml", "yaml", "port: 80", false},
+		{"invalid yaml", "yaml", "port: eighty", true},
+		{"text is not checked", "", "port=eighty", false},
+	}
+	for index, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CreateItem(f.alice, model.CreateItemRequest{ParentId: f.project.Id, Name: string(rune('a' + index)),
+				Content: tc.content, ContentType: tc.contentType})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("CreateItem() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestItemSchema(t *testing.T) {
+	f := newFixture(t)
+	mustOK(t)(SetSchema(f.alice, f.project.Id, basic.Resource_Type_PROJECT, portSchema))
+	item := f.createItem(t, model.CreateItemRequest{Name: "app", Content: `{"port": 80}`, ContentType: "json"})
+	mustOK(t)(SetSchema(f.alice, item.Id, basic.Resource_Type_ITEM, `{"properties": {"port": {"maximum": 1000}}}`))
+
+	// 草稿只校验格式, 发布时才按project和item的schema校验
+	mustOK(t)(SaveDraft(f.alice, item.Id, model.SaveDraftRequest{Content: `{"port": 8080}`}))
+	if _, err := PublishItem(f.alice, item.Id); err == nil {
+		t.Error("PublishItem() should fail the item schema")
+	}
+	mustOK(t)(SaveDraft(f.alice, item.Id, model.SaveDraftRequest{Content: `{"port": "80"}`}))
+	if _, err := PublishItem(f.alice, item.Id); err == nil {
+		t.Error("PublishItem() should fail the project schema")
+	}
+	mustOK(t)(SaveDraft(f.alice, item.Id, model.SaveDraftRequest{Content: `{"port": 443}`}))
+	mustOK(t)(PublishItem(f.alice, item.Id))
+
+	text := f.createItem(t, model.CreateItemRequest{Name: "notes", Content: "anything"})
+	if _, err := SetSchema(f.alice, text.Id, basic.Resource_Type_ITEM, portSchema); err == nil {
+		t.Error("SetSchema() on a text item should fail")
+	}
+}
+
+func TestSchemaVersions(t *testing.T) {
+	f := newFixture(t)
+	versions := func() []gin.H {
+		t.Helper()
+		result, err := ListSchema(f.alice, f.project.Id, basic.Resource_Type_PROJECT)
+		mustOK(t)(result, err)
+		return result["data"].([]gin.H)
+	}
+	mustOK(t)(SetSchema(f.alice, f.project.Id, basic.Resource_Type_PROJECT, portSchema))
+	// 仅格式不同的schema不产生新版本
+	mustOK(t)(SetSchema(f.alice, f.project.Id, basic.Resource_Type_PROJECT, "\n"+portSchema+"\n"))
+	if got := len(versions()); got != 1 {
+		t.Errorf("len(versions) = %d, want 1", got)
+	}
+	mustOK(t)(SetSchema(f.alice, f.project.Id, basic.Resource_Type_PROJECT, ""))
+	if got := versions(); len(got) != 2 || got[0]["schema"] != nil {
+		t.Errorf("versions = %v, want the removal on top", got)
+	}
+	mustOK(t)(CreateItem(f.alice, model.CreateItemRequest{ParentId: f.project.Id, Name: "app", Content: `{}`, ContentType: "json"}))
+
+	for _, schema := range []string{
+		`{"$ref": "http://127.0.0.1:1/schema.json"}`,
+		`{"$ref": "file:///etc/passwd"}`,
+		`{"type": "object"`,
+	} {
+		if _, err := SetSchema(f.alice, f.project.Id, basic.Resource_Type_PROJECT, schema); err == nil {
+			t.Errorf("SetSchema(%s) should fail", schema)
+		}
+	}
+	if _, err := SetSchema(f.bob, f.project.Id, basic.Resource_Type_PROJECT, portSchema); err == nil {
+		t.Error("SetSchema() without privilege should fail")
+	}
+}
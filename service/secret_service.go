@@ -0,0 +1,111 @@
+package service
+
+import (
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/keyring"
+	"zoe/model"
+)
+
+// 加密item的内容在item、版本和草稿中都以密文保存, 校验和输出前再解密
+func sealContent(item *model.Item, content string) (string, error) {
+	if item.Encrypted == 0 {
+		return content, nil
+	}
+	return keyring.Keys.Encrypt(content)
+}
+
+func openContent(item *model.Item, content string) (string, error) {
+	if item.Encrypted == 0 {
+		return content, nil
+	}
+	return keyring.Keys.Decrypt(content)
+}
+
+// 审计日志中不记录加密item的内容
+func maskContent(item *model.Item, content string) string {
+	if item.Encrypted == 0 {
+		return content
+	}
+	return basic.SECRET_MASK
+}
+
+// 加密item的明文只对有修改权限的用户可见, 只读用户看到掩码
+func canRevealItem(tx repo.Tx, user *model.User, item *model.Item) (bool, error) {
+	if item.Encrypted == 0 {
+		return true, nil
+	}
+	return repo.ValidateForUserModifyItem(tx, user, item.Id)
+}
+
+// 版本按自身记录的加密状态解密, 修改item的加密状态后历史版本保持原样
+func openVersion(version *model.Version) (string, error) {
+	if version.Encrypted == 0 {
+		return version.Content, nil
+	}
+	return keyring.Keys.Decrypt(version.Content)
+}
+
+// 把版本内容转换为item当前加密状态下的保存形式, 用于回滚和灰度全量
+func restoreVersionContent(item *model.Item, version *model.Version) (string, string, error) {
+	plaintext, err := openVersion(version)
+	if err != nil {
+		return "", "", err
+	}
+	if version.Encrypted == item.Encrypted {
+		return version.Content, plaintext, nil
+	}
+	content, err := sealContent(item, plaintext)
+	if err != nil {
+		return "", "", err
+	}
+	return content, plaintext, nil
+}
+
+func revealContent(item *model.Item, content string, reveal bool) (string, error) {
+	if !reveal {
+		return basic.SECRET_MASK, nil
+	}
+	return openContent(item, content)
+}
+
+// 修改item的加密状态, 当前内容和草稿一起加密或解密, 返回改写后的当前内容.
+// 历史版本不可修改, 调用方需为改写后的内容创建新版本; 取消加密时旧版本仍以密文保存
+func setItemEncrypted(tx repo.Tx, item *model.Item, encrypted int) (string, error) {
+	if item.Encrypted == encrypted {
+		return item.Content, nil
+	}
+	target := *item
+	target.Encrypted = encrypted
+	convert := func(content string) (string, error) {
+		plaintext, err := openContent(item, content)
+		if err != nil {
+			return "", err
+		}
+		return sealContent(&target, plaintext)
+	}
+	content, err := convert(item.Content)
+	if err != nil {
+		return "", err
+	}
+	if err = tx.Items().UpdateContent(item.Id, content); err != nil {
+		return "", err
+	}
+	draft, err := tx.Drafts().GetByItemId(item.Id)
+	if err != nil {
+		return "", err
+	}
+	if draft != nil {
+		draftContent, err := convert(draft.Content)
+		if err != nil {
+			return "", err
+		}
+		if err = tx.Drafts().Save(item.Id, draftContent, draft.UserId); err != nil {
+			return "", err
+		}
+	}
+	if err = tx.Items().UpdateEncrypted(item.Id, encrypted); err != nil {
+		return "", err
+	}
+	return content, nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"encoding/base64"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"testing"
+	"zoe/cache"
+	"zoe/config"
+	"zoe/dao/memory"
+	"zoe/dao/repo"
+	"zoe/keyring"
+	"zoe/model"
+)
+
+var testKeys = map[string]string{
+	"k1": base64.StdEncoding.EncodeToString([]byte(strings.Repeat("1", 32))),
+	"k2": base64.StdEncoding.EncodeToString([]byte(strings.Repeat("2", 32))),
+}
+
+// 每个测试使用独立的内存存储和缓存, 当前密钥为k1, 结束后恢复全局状态
+func setupStore(t *testing.T) {
+	t.Helper()
+	db, pullCache, keys, c := repo.DB, cache.PullCache, keyring.Keys, config.C
+	t.Cleanup(func() { repo.DB, cache.PullCache, keyring.Keys, config.C = db, pullCache, keys, c })
+	repo.DB = memory.NewStore()
+	cache.PullCache = cache.NewCache(60, 100)
+	keyring.Keys = newTestKeyring(t, "k1", testKeys)
+	config.C = &config.Config{}
+}
+
+func newTestKeyring(t *testing.T, keyId string, keys map[string]string) *keyring.Keyring {
+	t.Helper()
+	k, err := keyring.NewKeyring(keyId, keys)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return k
+}
+
+// 用法为mustOK(t)(CreateOrg(...)), 调用失败时终止测试, 成功时返回data
+func mustOK(t *testing.T) func(result gin.H, err error) gin.H {
+	return func(result gin.H, err error) gin.H {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result["code"] != 0 {
+			t.Fatalf("code = %v, want 0", result["code"])
+		}
+		data, _ := result["data"].(gin.H)
+		return data
+	}
+}
+
+// 在单独的事务中读取存储, 用于准备数据和检查结果
+func view(t *testing.T, fn func(tx repo.Tx) error) {
+	t.Helper()
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err = fn(tx); err != nil {
+		t.Fatalf("read store: %v", err)
+	}
+}
+
+func newUser(t *testing.T, name string) *model.User {
+	t.Helper()
+	mustOK(t)(Register(model.RegisterRequest{Name: name, Password: "pw123456"}))
+	var user *model.User
+	view(t, func(tx repo.Tx) (err error) {
+		user, err = tx.Users().GetByName(name)
+		return
+	})
+	return user
+}
+
+// acme组织及其下的web项目, 都是非公开的, web有prod和dev两个环境
+type fixture struct {
+	alice   *model.User
+	bob     *model.User
+	org     *model.Org
+	project *model.Project
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	setupStore(t)
+	f := &fixture{alice: newUser(t, "alice"), bob: newUser(t, "bob")}
+	mustOK(t)(CreateOrg(f.alice, model.OrgCreateRequest{Name: "acme", Private: true}))
+	view(t, func(tx repo.Tx) (err error) {
+		f.org, err = tx.Orgs().GetByName("acme")
+		return
+	})
+	mustOK(t)(CreateProject(f.alice, model.CreateProjectRequest{ParentId: f.org.Id, Name: "web", Private: "true"}))
+	view(t, func(tx repo.Tx) (err error) {
+		f.project, err = tx.Projects().GetByParentIdAndName(f.org.Id, "acme.web")
+		return
+	})
+	for _, env := range []string{"prod", "dev"} {
+		mustOK(t)(CreateEnvironment(f.alice, f.project.Id, model.CreateEnvironmentRequest{Name: env}))
+	}
+	return f
+}
+
+func (f *fixture) createItem(t *testing.T, req model.CreateItemRequest) *model.Item {
+	t.Helper()
+	req.ParentId = f.project.Id
+	if req.Private == "" {
+		req.Private = "true"
+	}
+	mustOK(t)(CreateItem(f.alice, req))
+	return f.item(t, req.Name, req.Environment)
+}
+
+func (f *fixture) item(t *testing.T, name, environment string) *model.Item {
+	t.Helper()
+	var item *model.Item
+	view(t, func(tx repo.Tx) (err error) {
+		item, err = tx.Items().GetByParentIdAndName(f.project.Id, f.project.Name+"."+name, environment)
+		return
+	})
+	return item
+}
+
+func (f *fixture) pull(t *testing.T, user *model.User, name, environment string) gin.H {
+	t.Helper()
+	return mustOK(t)(Pull(user, "", "", environment, "", "acme", "web", name))
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"time"
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/utils"
+)
+
+var ErrTokenNotAllowed = errors.New("API token不能用于管理token")
+
+func getTokenInfo(token *model.Token) gin.H {
+	scope := ""
+	switch token.ResourceType {
+	case basic.Resource_Type_ORG:
+		scope = "org"
+	case basic.Resource_Type_PROJECT:
+		scope = "project"
+	}
+	return gin.H{
+		"id":            token.Id,
+		"name":          token.Name,
+		"read_only":     token.ReadOnly == 1,
+		"resource_type": scope,
+		"resource_id":   token.ResourceId,
+		"expired_at":    token.ExpiredAt,
+		"last_used_at":  token.LastUsedAt,
+		"created_at":    token.CreateAt,
+	}
+}
+
+// token的作用范围只能是整个账号、某个组织或某个项目, 创建者至少要有该范围的拉取权限
+func getTokenScope(tx repo.Tx, user *model.User, req model.CreateTokenRequest) (int, int, error) {
+	var resType int
+	switch req.ResourceType {
+	case "":
+		return 0, 0, nil
+	case "org":
+		resType = basic.Resource_Type_ORG
+		org, err := tx.Orgs().GetById(req.ResourceId)
+		if err != nil {
+			return 0, 0, err
+		}
+		if org == nil {
+			return 0, 0, errors.New("组织不存在")
+		}
+	case "project":
+		resType = basic.Resource_Type_PROJECT
+		project, err := tx.Projects().GetById(req.ResourceId)
+		if err != nil {
+			return 0, 0, err
+		}
+		if project == nil {
+			return 0, 0, errors.New("项目不存在")
+		}
+	default:
+		return 0, 0, errors.New("token范围只能是org或project")
+	}
+	priType, err := repo.ResolvePrivilege(tx, user, req.ResourceId, resType)
+	if err != nil {
+		return 0, 0, err
+	}
+	if priType < basic.Privilege_Type_PULLER {
+		return 0, 0, errors.New("用户没有该范围的权限")
+	}
+	return resType, req.ResourceId, nil
+}
+
+// 明文token只在创建时返回一次, 数据库中只保存其摘要
+func CreateToken(user *model.User, req model.CreateTokenRequest) (gin.H, error) {
+	if user.Token != nil {
+		return nil, ErrTokenNotAllowed
+	}
+	if len(req.Name) >= basic.MAX_RESOURCE_NAME_LENGTH {
+		return nil, errors.New("token名称长度过长")
+	}
+	expireDays := req.ExpireDays
+	if expireDays <= 0 {
+		expireDays = basic.DEFAULT_TOKEN_DAYS
+	} else if expireDays > basic.MAX_TOKEN_DAYS {
+		return nil, fmt.Errorf("token有效期不能超过%d天", basic.MAX_TOKEN_DAYS)
+	}
+	secret, err := utils.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	raw := basic.API_TOKEN_PREFIX + secret
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	resType, resId, err := getTokenScope(tx, user, req)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	readOnly := 0
+	if req.ReadOnly {
+		readOnly = 1
+	}
+	expiredAt := time.Now().Add(time.Duration(expireDays) * 24 * time.Hour)
+	id, err := tx.Tokens().Create(user.Id, req.Name, utils.HashToken(raw), readOnly, resType, resId, expiredAt)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	token, err := tx.Tokens().GetById(id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	info := getTokenInfo(token)
+	info["token"] = raw
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": info,
+	}, nil
+}
+
+func ListToken(user *model.User) (gin.H, error) {
+	if user.Token != nil {
+		return nil, ErrTokenNotAllowed
+	}
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := tx.Tokens().ListByUserId(user.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	data := make([]gin.H, 0, len(*tokens))
+	for index := range *tokens {
+		data = append(data, getTokenInfo(&(*tokens)[index]))
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": data,
+	}, nil
+}
+
+func DeleteToken(user *model.User, tokenId int) (gin.H, error) {
+	if user.Token != nil {
+		return nil, ErrTokenNotAllowed
+	}
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	token, err := tx.Tokens().GetById(tokenId)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if token == nil || token.UserId != user.Id {
+		_ = tx.Rollback()
+		return nil, errors.New("token不存在")
+	}
+	if err = tx.Tokens().Delete(tokenId); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{"code": 0, "msg": "OK"}, nil
+}
+
+// 限定范围的token所属的组织id, 未限定范围时返回0
+func getTokenScopeOrgId(tx repo.Tx, user *model.User) (int, error) {
+	token := user.Token
+	if token == nil {
+		return 0, nil
+	}
+	switch token.ResourceType {
+	case basic.Resource_Type_ORG:
+		return token.ResourceId, nil
+	case basic.Resource_Type_PROJECT:
+		project, err := tx.Projects().GetById(token.ResourceId)
+		if err != nil {
+			return 0, err
+		}
+		if project == nil {
+			return -1, nil
+		}
+		return project.ParentId, nil
+	}
+	return 0, nil
+}
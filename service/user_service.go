@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/utils"
+)
+
+func getUserInfo(user *model.User) gin.H {
+	return gin.H{
+		"id":         user.Id,
+		"name":       user.Name,
+		"created_at": user.CreateAt,
+	}
+}
+
+func Register(req model.RegisterRequest) (gin.H, error) {
+	if len(req.Name) >= basic.MAX_USER_NAME_LENGTH {
+		return nil, errors.New("用户名长度过长")
+	}
+	if strings.ContainsAny(req.Name, " .") {
+		return nil, errors.New("用户名不能包含空格和.")
+	}
+	if len(req.Password) < basic.MIN_PASSWORD_LENGTH {
+		return nil, errors.New("密码长度过短")
+	}
+	secretHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	userHash, err := utils.GenerateToken(16)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	user, err := tx.Users().GetByName(req.Name)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if user != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("用户名已经存在")
+	}
+	id, err := tx.Users().Create(req.Name, userHash, secretHash)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": gin.H{
+			"id":   id,
+			"name": req.Name,
+		},
+	}, nil
+}
+
+// 登录成功返回会话token, 数据库中只保存其摘要
+func Login(req model.LoginRequest) (gin.H, string, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, "", err
+	}
+	user, err := tx.Users().GetByName(req.Name)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, "", err
+	}
+	if user == nil || !utils.CheckPassword(user.SecretHash, req.Password) {
+		_ = tx.Rollback()
+		return nil, "", errors.New("用户名或密码错误")
+	}
+	token, err := utils.GenerateToken(32)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, "", err
+	}
+	expiredAt := time.Now().Add(basic.SESSION_TTL_HOURS * time.Hour)
+	if _, err = tx.Sessions().Create(user.Id, utils.HashToken(token), expiredAt); err != nil {
+		_ = tx.Rollback()
+		return nil, "", err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, "", err
+	}
+	userInfo := getUserInfo(user)
+	userInfo["token"] = token
+	userInfo["expired_at"] = expiredAt
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": userInfo,
+	}, token, nil
+}
+
+func Logout(token string) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Sessions().Delete(utils.HashToken(token)); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{"code": 0, "msg": "OK"}, nil
+}
+
+// API token只能访问其范围内的资源, 不能查看所属用户的信息
+func CurrentUser(user *model.User) (gin.H, error) {
+	if user.Token != nil {
+		return nil, errors.New("API token不能查看用户信息")
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": getUserInfo(user),
+	}, nil
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"zoe/basic"
+	"zoe/dao/repo"
+	"zoe/model"
+	"zoe/utils"
+)
+
+func createVersion(tx repo.Tx, userId, resId, resType int, resName, content string, visibility, encrypted int) (int, error) {
+	versionId, err := tx.Versions().Create(resName, content, resId, resType, visibility, encrypted, userId, 0)
+	if err != nil {
+		return 0, err
+	}
+	if err = updateCurrentVersionId(tx, resId, resType, versionId); err != nil {
+		return 0, err
+	}
+	return versionId, nil
+}
+
+func updateCurrentVersionId(tx repo.Tx, resId, resType, versionId int) error {
+	if resType == basic.Resource_Type_ORG {
+		return tx.Orgs().UpdateCurrentVersionId(resId, versionId)
+	} else if resType == basic.Resource_Type_PROJECT {
+		return tx.Projects().UpdateCurrentVersionId(resId, versionId)
+	} else if resType == basic.Resource_Type_ITEM {
+		return tx.Items().UpdateCurrentVersionId(resId, versionId)
+	}
+	return errors.New("非法的资源类型")
+}
+
+func validateForUserViewResource(tx repo.Tx, user *model.User, resId, resType int) (bool, error) {
+	if resType == basic.Resource_Type_ORG {
+		org, err := tx.Orgs().GetById(resId)
+		if org == nil || err != nil {
+			return false, errors.New("不存在的组织")
+		}
+		return repo.ValidateForUserViewOrg(tx, user, resId)
+	} else if resType == basic.Resource_Type_PROJECT {
+		project, err := tx.Projects().GetById(resId)
+		if project == nil || err != nil {
+			return false, errors.New("目标项目不存在")
+		}
+		return repo.ValidateForUserViewProject(tx, user, project.Id)
+	} else if resType == basic.Resource_Type_ITEM {
+		item, err := tx.Items().GetById(resId)
+		if item == nil || err != nil {
+			return false, errors.New("目标item不存在")
+		}
+		return repo.ValidateForUserViewItem(tx, user, item.Id)
+	}
+	return false, errors.New("非法的资源类型")
+}
+
+// 加密保存的版本只对有修改权限的用户解密, 只读用户看到掩码; 返回是否有内容被打码
+func revealVersions(tx repo.Tx, user *model.User, resId, resType int, versions []model.Version) (bool, error) {
+	if resType != basic.Resource_Type_ITEM {
+		return false, nil
+	}
+	reveal := true
+	for _, version := range versions {
+		if version.Encrypted == 1 {
+			flag, err := repo.ValidateForUserModifyItem(tx, user, resId)
+			if err != nil {
+				return false, err
+			}
+			reveal = flag
+			break
+		}
+	}
+	masked := false
+	for index := range versions {
+		if versions[index].Encrypted == 0 {
+			continue
+		}
+		if !reveal {
+			versions[index].Content = basic.SECRET_MASK
+			masked = true
+			continue
+		}
+		content, err := openVersion(&versions[index])
+		if err != nil {
+			return false, err
+		}
+		versions[index].Content = content
+	}
+	return masked, nil
+}
+
+func ListVersion(user *model.User, resId, resType int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	flag, err := validateForUserViewResource(tx, user, resId, resType)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限查看该资源")
+	}
+	versions, err := tx.Versions().ListByResource(resId, resType)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if _, err = revealVersions(tx, user, resId, resType, *versions); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	userIds := make([]int, len(*versions))
+	for index, version := range *versions {
+		userIds[index] = version.UserId
+	}
+	users, err := tx.Users().ListByIds(userIds)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": utils.GetVersionInfo(versions, users),
+	}, nil
+}
+
+func SingleVersion(user *model.User, versionId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	version, err := tx.Versions().GetById(versionId)
+	if version == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标版本不存在")
+	}
+	flag, err := validateForUserViewResource(tx, user, version.ResourceId, version.ResourceType)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限查看该资源")
+	}
+	versions := []model.Version{*version}
+	if _, err = revealVersions(tx, user, version.ResourceId, version.ResourceType, versions); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	users, err := tx.Users().ListByIds([]int{version.UserId})
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": utils.GetVersionInfo(&versions, users)[0],
+	}, nil
+}
+
+func DiffVersion(user *model.User, versionId, baseVersionId int) (gin.H, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	version, err := tx.Versions().GetById(versionId)
+	if version == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("目标版本不存在")
+	}
+	baseVersion, err := tx.Versions().GetById(baseVersionId)
+	if baseVersion == nil || err != nil {
+		_ = tx.Rollback()
+		return nil, errors.New("对比版本不存在")
+	}
+	if version.ResourceId != baseVersion.ResourceId || version.ResourceType != baseVersion.ResourceType {
+		_ = tx.Rollback()
+		return nil, errors.New("只能对比同一资源的版本")
+	}
+	flag, err := validateForUserViewResource(tx, user, version.ResourceId, version.ResourceType)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if !flag {
+		_ = tx.Rollback()
+		return nil, errors.New("用户无权限查看该资源")
+	}
+	versions := []model.Version{*baseVersion, *version}
+	masked, err := revealVersions(tx, user, version.ResourceId, version.ResourceType, versions)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	// 打码后的内容没有可比性, 只返回空的差异
+	lines := []gin.H{}
+	if !masked {
+		if lines, err = utils.DiffLines(versions[0].Content, versions[1].Content); err != nil {
+			return nil, err
+		}
+	}
+	return gin.H{
+		"code": 0,
+		"msg":  "OK",
+		"data": gin.H{
+			"from":               baseVersion.Id,
+			"to":                 version.Id,
+			"visibility_changed": version.Visibility != baseVersion.Visibility,
+			"masked":             masked,
+			"lines":              lines,
+		},
+	}, nil
+}
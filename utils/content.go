@@ -0,0 +1,412 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"zoe/basic"
+)
+
+// 内容解析失败的位置, Line和Column从1开始, 解析器不提供列号时Column为0
+type ContentError struct {
+	ContentType string
+	Line        int
+	Column      int
+	Message     string
+}
+
+func (e *ContentError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("%s格式错误, 第%d行第%d列: %s", e.ContentType, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s格式错误, 第%d行: %s", e.ContentType, e.Line, e.Message)
+}
+
+func ParseContentType(contentType string) (string, error) {
+	switch contentType {
+	case "":
+		return basic.Content_Type_TEXT, nil
+	case basic.Content_Type_TEXT, basic.Content_Type_JSON, basic.Content_Type_YAML, basic.Content_Type_TOML,
+		basic.Content_Type_INI, basic.Content_Type_PROPERTIES:
+		return contentType, nil
+	}
+	return "", errors.New("内容格式只能是text、json、yaml、toml、ini或properties")
+}
+
+// 按字节偏移计算行列号, 列号按字符计
+func offsetPosition(content string, offset int) (int, int) {
+	if offset > len(content) {
+		offset = len(content)
+	}
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+	return line, column
+}
+
+// 校验内容是否符合声明的格式, 空内容对任何格式都合法
+func ValidateContent(contentType, content string) error {
+	_, err := parseContent(contentType, content)
+	return err
+}
+
+// 把内容解析为通用的map/slice/标量结构, text格式原样返回字符串
+func parseContent(contentType, content string) (interface{}, error) {
+	if contentType == basic.Content_Type_TEXT || contentType == "" {
+		return content, nil
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+	switch contentType {
+	case basic.Content_Type_JSON:
+		return parseJson(content)
+	case basic.Content_Type_YAML:
+		return parseYaml(content)
+	case basic.Content_Type_TOML:
+		return parseToml(content)
+	case basic.Content_Type_INI:
+		return parseIni(content)
+	case basic.Content_Type_PROPERTIES:
+		return parseProperties(content)
+	}
+	return nil, fmt.Errorf("未知的内容格式: %s", contentType)
+}
+
+func parseJson(content string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err == nil {
+		// 一个item只能有一个JSON值
+		offset := int(decoder.InputOffset())
+		rest := content[offset:]
+		if trimmed := strings.TrimLeft(rest, " \t\r\n"); trimmed != "" {
+			line, column := offsetPosition(content, offset+len(rest)-len(trimmed))
+			return nil, &ContentError{basic.Content_Type_JSON, line, column, "JSON值之后还有多余的内容"}
+		}
+		return normalizeValue(value), nil
+	}
+	offset := len(content)
+	if syntaxErr, ok := err.(*json.SyntaxError); ok && syntaxErr.Offset > 0 {
+		// Offset是出错字符之后的位置
+		offset = int(syntaxErr.Offset) - 1
+	}
+	line, column := offsetPosition(content, offset)
+	message := err.Error()
+	if err.Error() == "EOF" || err.Error() == "unexpected EOF" {
+		message = "unexpected end of JSON input"
+	}
+	return nil, &ContentError{basic.Content_Type_JSON, line, column, message}
+}
+
+var yamlLinePattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// yaml.v2的错误只带行号, 列号由yamlErrorColumn推算
+func parseYaml(content string) (interface{}, error) {
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(content), &value); err != nil {
+		line, message := splitYamlError(err)
+		if line > 0 {
+			column := yamlErrorColumn(content, line, line, message)
+			return nil, &ContentError{basic.Content_Type_YAML, line, column, message}
+		}
+		// 出错位置在第一行时yaml.v2不带行号
+		if column := yamlErrorColumn(content, 1, 0, message); column > 0 {
+			return nil, &ContentError{basic.Content_Type_YAML, 1, column, message}
+		}
+		line = strings.Count(content, "\n") + 1
+		return nil, &ContentError{ContentType: basic.Content_Type_YAML, Line: line, Message: message}
+	}
+	return normalizeValue(value), nil
+}
+
+func splitYamlError(err error) (int, string) {
+	if match := yamlLinePattern.FindStringSubmatch(err.Error()); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return line, match[2]
+	}
+	return 0, strings.TrimPrefix(err.Error(), "yaml: ")
+}
+
+// 在出错行上二分查找仍能复现同一错误(errLine为错误中的行号)的最短前缀, 其最后一个字符即出错的列;
+// 不含该行也会报错(如意外结束)或只截取该行无法复现时返回0
+func yamlErrorColumn(content string, line, errLine int, message string) int {
+	lines := strings.SplitAfter(content, "\n")
+	if line > len(lines) {
+		return 0
+	}
+	start := 0
+	for _, text := range lines[:line-1] {
+		start += len(text)
+	}
+	text := strings.TrimRight(lines[line-1], "\r\n")
+	fails := func(end int) bool {
+		var value interface{}
+		err := yaml.Unmarshal([]byte(content[:start+end]), &value)
+		if err == nil {
+			return false
+		}
+		prefixLine, prefixMessage := splitYamlError(err)
+		return prefixLine == errLine && prefixMessage == message
+	}
+	if fails(0) || !fails(len(text)) {
+		return 0
+	}
+	var ends []int
+	for index := range text {
+		if index > 0 {
+			ends = append(ends, index)
+		}
+	}
+	ends = append(ends, len(text))
+	found := sort.Search(len(ends), func(i int) bool { return fails(ends[i]) })
+	return utf8.RuneCountInString(text[:ends[found]])
+}
+
+var tomlPrefixPattern = regexp.MustCompile(`^toml: line \d+( \(last key ".*"\))?: `)
+
+func parseToml(content string) (interface{}, error) {
+	value := make(map[string]interface{})
+	if _, err := toml.Decode(content, &value); err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			_, column := offsetPosition(content, parseErr.Position.Start)
+			message := parseErr.Message
+			if message == "" {
+				message = tomlPrefixPattern.ReplaceAllString(parseErr.Error(), "")
+			}
+			return nil, &ContentError{basic.Content_Type_TOML, parseErr.Position.Line, column, message}
+		}
+		return nil, err
+	}
+	return normalizeValue(value), nil
+}
+
+// 段外的键放在顶层, 每个段是一个子map; 同一段内不允许重复的键
+func parseIni(content string) (interface{}, error) {
+	result := make(map[string]interface{})
+	section := result
+	for index, raw := range strings.Split(content, "\n") {
+		line := strings.TrimRight(raw, "\r")
+		trimmed := strings.TrimSpace(line)
+		column := len(line) - len(strings.TrimLeft(line, " \t")) + 1
+		if trimmed == "" || trimmed[0] == ';' || trimmed[0] == '#' {
+			continue
+		}
+		if trimmed[0] == '[' {
+			end := strings.Index(trimmed, "]")
+			if end < 0 {
+				return nil, &ContentError{basic.Content_Type_INI, index + 1, column + len(trimmed), "段名缺少]"}
+			}
+			rest := strings.TrimSpace(trimmed[end+1:])
+			if rest != "" && rest[0] != ';' && rest[0] != '#' {
+				return nil, &ContentError{basic.Content_Type_INI, index + 1, column + end + 1, "段名之后有多余的内容"}
+			}
+			name := strings.TrimSpace(trimmed[1:end])
+			if name == "" {
+				return nil, &ContentError{basic.Content_Type_INI, index + 1, column + 1, "段名不能为空"}
+			}
+			if existing, ok := result[name]; ok {
+				if _, isSection := existing.(map[string]interface{}); !isSection {
+					return nil, &ContentError{basic.Content_Type_INI, index + 1, column + 1, "段名与顶层的键重复: " + name}
+				}
+				section = existing.(map[string]interface{})
+			} else {
+				section = make(map[string]interface{})
+				result[name] = section
+			}
+			continue
+		}
+		sep := strings.IndexAny(trimmed, "=:")
+		if sep < 0 {
+			return nil, &ContentError{basic.Content_Type_INI, index + 1, column, "缺少=或:"}
+		}
+		key := strings.TrimSpace(trimmed[:sep])
+		if key == "" {
+			return nil, &ContentError{basic.Content_Type_INI, index + 1, column, "键不能为空"}
+		}
+		if _, ok := section[key]; ok {
+			return nil, &ContentError{basic.Content_Type_INI, index + 1, column, "重复的键: " + key}
+		}
+		section[key] = strings.TrimSpace(trimmed[sep+1:])
+	}
+	return result, nil
+}
+
+// 按java properties的规则解析: 支持=、:或空白分隔, 行尾\续行和\uXXXX转义
+func parseProperties(content string) (interface{}, error) {
+	result := make(map[string]interface{})
+	lines := strings.Split(content, "\n")
+	for index := 0; index < len(lines); index++ {
+		lineNo := index + 1
+		line := strings.TrimLeft(strings.TrimRight(lines[index], "\r"), " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		column := len(strings.TrimRight(lines[index], "\r")) - len(line) + 1
+		// 行尾奇数个\表示续行
+		for continues(line) && index+1 < len(lines) {
+			index++
+			line = line[:len(line)-1] + strings.TrimLeft(strings.TrimRight(lines[index], "\r"), " \t\f")
+		}
+		keyEnd := len(line)
+		for i := 0; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+				continue
+			}
+			if line[i] == '=' || line[i] == ':' || line[i] == ' ' || line[i] == '\t' || line[i] == '\f' {
+				keyEnd = i
+				break
+			}
+		}
+		key, err := unescapeProperty(line[:keyEnd], lineNo, column)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			return nil, &ContentError{basic.Content_Type_PROPERTIES, lineNo, column, "键不能为空"}
+		}
+		rest := strings.TrimLeft(line[keyEnd:], " \t\f")
+		if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+			rest = strings.TrimLeft(rest[1:], " \t\f")
+		}
+		value, err := unescapeProperty(rest, lineNo, column+len(line)-len(rest))
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+func continues(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count%2 == 1
+}
+
+func unescapeProperty(str string, line, column int) (string, error) {
+	if !strings.Contains(str, "\\") {
+		return str, nil
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(str); i++ {
+		if str[i] != '\\' || i+1 >= len(str) {
+			buf.WriteByte(str[i])
+			continue
+		}
+		i++
+		switch str[i] {
+		case 't':
+			buf.WriteByte('\t')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'u':
+			if i+5 > len(str) {
+				return "", &ContentError{basic.Content_Type_PROPERTIES, line, column + i - 1, "不完整的\\u转义"}
+			}
+			code, err := strconv.ParseUint(str[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", &ContentError{basic.Content_Type_PROPERTIES, line, column + i - 1, "非法的\\u转义: \\u" + str[i+1:i+5]}
+			}
+			buf.WriteRune(rune(code))
+			i += 4
+		default:
+			buf.WriteByte(str[i])
+		}
+	}
+	return buf.String(), nil
+}
+
+// 统一成map[string]interface{}, 数字转成int64或float64, 便于再编码为其他格式
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			result[fmt.Sprint(key)] = normalizeValue(elem)
+		}
+		return result
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = normalizeValue(elem)
+		}
+		return v
+	case []interface{}:
+		for index, elem := range v {
+			v[index] = normalizeValue(elem)
+		}
+		return v
+	case []map[string]interface{}:
+		result := make([]interface{}, len(v))
+		for index, elem := range v {
+			result[index] = normalizeValue(elem)
+		}
+		return result
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
+
+// 把内容转换为format指定的格式输出, 目前支持json、yaml和toml
+func RenderContent(contentType, content, format string) (string, error) {
+	if contentType == basic.Content_Type_TEXT || contentType == "" {
+		return "", errors.New("纯文本内容不能转换格式")
+	}
+	value, err := parseContent(contentType, content)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case basic.Content_Type_JSON:
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case basic.Content_Type_YAML:
+		if value == nil {
+			return "", nil
+		}
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case basic.Content_Type_TOML:
+		if value == nil {
+			return "", nil
+		}
+		if _, ok := value.(map[string]interface{}); !ok {
+			return "", errors.New("只有顶层为对象的内容才能转换为toml")
+		}
+		var buf bytes.Buffer
+		if err = toml.NewEncoder(&buf).Encode(value); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	return "", errors.New("输出格式只能是json、yaml或toml")
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+	"zoe/basic"
+)
+
+func TestValidateYamlPosition(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		line    int
+		column  int
+	}{
+		{"first line", "a: b: c", 1, 5},
+		{"later line", "a: 1\nb: x: 2\n", 2, 5},
+		{"unicode", "a: 1\nb: 中文: 2", 2, 6},
+		{"tab indent", "a:\n\t- b", 2, 1},
+		{"unclosed flow", "{a: 1", 1, 5},
+		{"unexpected end", "k: \"abc\n", 2, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateContent(basic.Content_Type_YAML, tc.content)
+			contentErr, ok := err.(*ContentError)
+			if !ok {
+				t.Fatalf("ValidateContent() error = %v, want *ContentError", err)
+			}
+			if contentErr.Line != tc.line || contentErr.Column != tc.column {
+				t.Errorf("position = %d:%d, want %d:%d", contentErr.Line, contentErr.Column, tc.line, tc.column)
+			}
+		})
+	}
+}
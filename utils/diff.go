@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"zoe/basic"
+)
+
+var ErrDiffTooLarge = errors.New("内容过大, 无法对比")
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// 基于最长公共子序列的行级diff, op: "+" 新增, "-" 删除, " " 未变
+// 首尾相同的行不参与LCS计算, 剩余部分超过MAX_DIFF_CELLS时返回ErrDiffTooLarge
+func DiffLines(oldContent, newContent string) ([]gin.H, error) {
+	a := splitLines(oldContent)
+	b := splitLines(newContent)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	lines := []gin.H{}
+	for _, line := range a[:prefix] {
+		lines = append(lines, gin.H{"op": " ", "line": line})
+	}
+	common := a[len(a)-suffix:]
+	a = a[prefix : len(a)-suffix]
+	b = b[prefix : len(b)-suffix]
+	if len(a) > 0 && len(b) > basic.MAX_DIFF_CELLS/len(a) {
+		return nil, ErrDiffTooLarge
+	}
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			lines = append(lines, gin.H{"op": " ", "line": a[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			lines = append(lines, gin.H{"op": "-", "line": a[i]})
+			i++
+		} else {
+			lines = append(lines, gin.H{"op": "+", "line": b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, gin.H{"op": "-", "line": a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, gin.H{"op": "+", "line": b[j]})
+	}
+	for _, line := range common {
+		lines = append(lines, gin.H{"op": " ", "line": line})
+	}
+	return lines, nil
+}
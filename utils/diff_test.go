@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"github.com/gin-gonic/gin"
+	"strings"
+	"testing"
+	"zoe/basic"
+)
+
+func formatDiff(lines []gin.H) string {
+	var result []string
+	for _, line := range lines {
+		result = append(result, line["op"].(string)+line["line"].(string))
+	}
+	return strings.Join(result, "|")
+}
+
+func TestDiffLines(t *testing.T) {
+	cases := []struct {
+		name       string
+		oldContent string
+		newContent string
+		want       string
+	}{
+		{"both empty", "", "", ""},
+		{"added to empty", "", "a\nb", "+a|+b"},
+		{"removed all", "a\nb", "", "-a|-b"},
+		{"identical", "a\nb\nc", "a\nb\nc", " a| b| c"},
+		{"trailing newline ignored", "a\nb\n", "a\nb", " a| b"},
+		{"changed middle", "a\nb\nc", "a\nx\nc", " a|-b|+x| c"},
+		{"appended", "a\nb", "a\nb\nc", " a| b|+c"},
+		{"prepended", "b\nc", "a\nb\nc", "+a| b| c"},
+		{"duplicate lines", "a\na", "a", " a|-a"},
+		{"interleaved", "a\nb\nc\nd", "b\nx\nd\ne", "-a| b|-c|+x| d|+e"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			lines, err := DiffLines(tc.oldContent, tc.newContent)
+			if err != nil {
+				t.Fatalf("DiffLines() error = %v", err)
+			}
+			if lines == nil {
+				t.Fatal("DiffLines() should not return nil")
+			}
+			if got := formatDiff(lines); got != tc.want {
+				t.Errorf("DiffLines() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesTooLarge(t *testing.T) {
+	size := 2001
+	if size*size <= basic.MAX_DIFF_CELLS {
+		t.Fatalf("size %d does not exceed MAX_DIFF_CELLS", size)
+	}
+	same := strings.Repeat("same\n", size)
+	cases := []struct {
+		name       string
+		oldContent string
+		newContent string
+		wantErr    bool
+	}{
+		{"identical large", same, same, false},
+		{"large with small change", same + "a", same + "b", false},
+		{"large disjoint", strings.Repeat("a\n", size), strings.Repeat("b\n", size), true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DiffLines(tc.oldContent, tc.newContent)
+			if tc.wantErr && err != ErrDiffTooLarge {
+				t.Errorf("DiffLines() error = %v, want %v", err, ErrDiffTooLarge)
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("DiffLines() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"errors"
+	"hash/crc32"
+	"net"
+	"strings"
+)
+
+type GrayRule struct {
+	ClientIds  []string
+	IpNets     []*net.IPNet
+	Percentage int
+}
+
+func SplitList(str string) []string {
+	var list []string
+	for _, s := range strings.Split(str, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// 合并两个列表并去重, 保持原有顺序
+func MergeList(a, b []string) []string {
+	seen := make(map[string]bool)
+	var list []string
+	for _, s := range append(append([]string{}, a...), b...) {
+		if s = strings.TrimSpace(s); s != "" && !seen[s] {
+			seen[s] = true
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// client id和ip段以逗号拼接存储, client id中不能出现逗号
+func ValidateClientIds(clientIds []string) error {
+	for _, id := range clientIds {
+		if strings.Contains(id, ",") {
+			return errors.New("client id不能包含逗号: " + id)
+		}
+	}
+	return nil
+}
+
+// 校验并规范化ip段, 单个ip视为/32或/128
+func NormalizeIpRanges(ranges []string) ([]string, error) {
+	list := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		r = strings.TrimSpace(r)
+		if !strings.Contains(r, "/") {
+			ip := net.ParseIP(r)
+			if ip == nil {
+				return nil, errors.New("非法的ip段: " + r)
+			}
+			if ip.To4() != nil {
+				r = r + "/32"
+			} else {
+				r = r + "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(r)
+		if err != nil {
+			return nil, errors.New("非法的ip段: " + r)
+		}
+		list = append(list, ipNet.String())
+	}
+	return list, nil
+}
+
+func NewGrayRule(clientIds, ipRanges string, percentage int) (*GrayRule, error) {
+	rule := &GrayRule{
+		ClientIds:  SplitList(clientIds),
+		Percentage: percentage,
+	}
+	for _, r := range SplitList(ipRanges) {
+		_, ipNet, err := net.ParseCIDR(r)
+		if err != nil {
+			return nil, err
+		}
+		rule.IpNets = append(rule.IpNets, ipNet)
+	}
+	return rule, nil
+}
+
+// 命中任一条件即进入灰度: 指定client id, ip段, 或client id哈希落在百分比内
+func (r *GrayRule) Match(resName, clientId, clientIp string) bool {
+	if clientId != "" {
+		for _, id := range r.ClientIds {
+			if id == clientId {
+				return true
+			}
+		}
+	}
+	if ip := net.ParseIP(clientIp); ip != nil {
+		for _, ipNet := range r.IpNets {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		}
+	}
+	if clientId != "" && r.Percentage > 0 {
+		bucket := crc32.ChecksumIEEE([]byte(resName+"/"+clientId)) % 100
+		if int(bucket) < r.Percentage {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"fmt"
+	"hash/crc32"
+	"testing"
+)
+
+// 找到落在指定桶中的client id
+func clientIdInBucket(resName string, bucket uint32) string {
+	for index := 0; ; index++ {
+		clientId := fmt.Sprintf("client-%d", index)
+		if crc32.ChecksumIEEE([]byte(resName+"/"+clientId))%100 == bucket {
+			return clientId
+		}
+	}
+}
+
+func TestGrayRuleMatch(t *testing.T) {
+	resName := "acme.web.db"
+	cases := []struct {
+		name       string
+		clientIds  string
+		ipRanges   string
+		percentage int
+		clientId   string
+		clientIp   string
+		want       bool
+	}{
+		{"no rule", "", "", 0, "a", "10.0.0.1", false},
+		{"client id", "a,b", "", 0, "b", "", true},
+		{"client id is not a prefix", "abc", "", 0, "ab", "", false},
+		{"empty client id", "a", "", 0, "", "", false},
+		{"ip in range", "", "10.0.0.0/24", 0, "", "10.0.0.255", true},
+		{"ip out of range", "", "10.0.0.0/24", 0, "", "10.0.1.0", false},
+		{"single ip", "", "10.0.0.1/32", 0, "", "10.0.0.1", true},
+		{"ipv6", "", "fd00::/8", 0, "", "fd00::1", true},
+		{"invalid client ip", "", "10.0.0.0/24", 0, "", "bad", false},
+		{"bucket 0 at 1%", "", "", 1, clientIdInBucket(resName, 0), "", true},
+		{"bucket 1 at 1%", "", "", 1, clientIdInBucket(resName, 1), "", false},
+		{"bucket 49 at 50%", "", "", 50, clientIdInBucket(resName, 49), "", true},
+		{"bucket 50 at 50%", "", "", 50, clientIdInBucket(resName, 50), "", false},
+		{"bucket 99 at 100%", "", "", 100, clientIdInBucket(resName, 99), "", true},
+		{"bucket 0 at 0%", "", "", 0, clientIdInBucket(resName, 0), "", false},
+		{"percentage without client id", "", "", 100, "", "10.0.0.1", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := NewGrayRule(tc.clientIds, tc.ipRanges, tc.percentage)
+			if err != nil {
+				t.Fatalf("NewGrayRule() error = %v", err)
+			}
+			if got := rule.Match(resName, tc.clientId, tc.clientIp); got != tc.want {
+				t.Errorf("Match() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewGrayRuleBadCidr(t *testing.T) {
+	for _, ipRanges := range []string{"10.0.0.0/33", "10.0.0.1", "bad/8", "10.0.0.0/24,300.0.0.0/8"} {
+		if _, err := NewGrayRule("", ipRanges, 0); err == nil {
+			t.Errorf("NewGrayRule(%q) should fail", ipRanges)
+		}
+	}
+}
+
+func TestNormalizeIpRanges(t *testing.T) {
+	cases := []struct {
+		name    string
+		ranges  []string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", nil, []string{}, false},
+		{"ipv4", []string{" 10.0.0.1 "}, []string{"10.0.0.1/32"}, false},
+		{"ipv6", []string{"fd00::1"}, []string{"fd00::1/128"}, false},
+		{"host bits", []string{"10.0.0.7/24"}, []string{"10.0.0.0/24"}, false},
+		{"bad ip", []string{"10.0.0"}, nil, true},
+		{"bad mask", []string{"10.0.0.0/33"}, nil, true},
+		{"bad cidr", []string{"10.0.0.0/24", "x/8"}, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeIpRanges(tc.ranges)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NormalizeIpRanges() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("NormalizeIpRanges() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateClientIds(t *testing.T) {
+	cases := []struct {
+		clientIds []string
+		wantErr   bool
+	}{
+		{nil, false},
+		{[]string{"a", "b-1"}, false},
+		{[]string{"a", "b,c"}, true},
+		{[]string{","}, true},
+	}
+	for _, tc := range cases {
+		if err := ValidateClientIds(tc.clientIds); (err != nil) != tc.wantErr {
+			t.Errorf("ValidateClientIds(%q) error = %v, wantErr %v", tc.clientIds, err, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"net"
+	"strings"
+)
+
+func ParseIpNets(ranges []string) ([]*net.IPNet, error) {
+	normalized, err := NormalizeIpRanges(ranges)
+	if err != nil {
+		return nil, err
+	}
+	ipNets := make([]*net.IPNet, 0, len(normalized))
+	for _, r := range normalized {
+		_, ipNet, _ := net.ParseCIDR(r)
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+func containsIp(ipNets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 只有直连地址是可信代理时才采信X-Forwarded-For: 从右向左跳过可信代理, 取第一个不可信的地址,
+// 遇到非法地址时停止并使用最后一个可信的地址
+func ResolveClientIp(remoteAddr, forwardedFor string, trustedProxies []*net.IPNet) string {
+	clientIp := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		clientIp = host
+	}
+	ip := net.ParseIP(clientIp)
+	if ip == nil || !containsIp(trustedProxies, ip) || forwardedFor == "" {
+		return clientIp
+	}
+	hops := strings.Split(forwardedFor, ",")
+	for index := len(hops) - 1; index >= 0; index-- {
+		hop := strings.TrimSpace(hops[index])
+		ip = net.ParseIP(hop)
+		if ip == nil {
+			break
+		}
+		clientIp = hop
+		if !containsIp(trustedProxies, ip) {
+			break
+		}
+	}
+	return clientIp
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"net"
+	"testing"
+)
+
+func TestResolveClientIp(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	trusted := []*net.IPNet{proxies}
+	cases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		trusted      []*net.IPNet
+		want         string
+	}{
+		{"direct", "1.2.3.4:5678", "", trusted, "1.2.3.4"},
+		{"untrusted remote ignores header", "1.2.3.4:5678", "9.9.9.9", trusted, "1.2.3.4"},
+		{"no trusted proxies", "10.0.0.1:5678", "9.9.9.9", nil, "10.0.0.1"},
+		{"trusted proxy", "10.0.0.1:5678", "9.9.9.9", trusted, "9.9.9.9"},
+		{"spoofed leftmost hop", "10.0.0.1:5678", "1.1.1.1, 9.9.9.9", trusted, "9.9.9.9"},
+		{"proxy chain", "10.0.0.1:5678", "9.9.9.9, 10.0.0.2", trusted, "9.9.9.9"},
+		{"all trusted", "10.0.0.1:5678", "10.0.0.3, 10.0.0.2", trusted, "10.0.0.3"},
+		{"invalid hop", "10.0.0.1:5678", "9.9.9.9, bad, 10.0.0.2", trusted, "10.0.0.2"},
+		{"ipv6 remote", "[fd00::1]:5678", "9.9.9.9", trusted, "fd00::1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ResolveClientIp(tc.remoteAddr, tc.forwardedFor, tc.trusted); got != tc.want {
+				t.Errorf("ResolveClientIp() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"zoe/basic"
+)
+
+// page从1开始, pageSize默认为DEFAULT_PAGE_SIZE且不超过MAX_PAGE_SIZE
+func NormalizePage(page, pageSize int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = basic.DEFAULT_PAGE_SIZE
+	} else if pageSize > basic.MAX_PAGE_SIZE {
+		pageSize = basic.MAX_PAGE_SIZE
+	}
+	return page, pageSize
+}
+
+// 排序参数为"字段"或"-字段", 前缀-表示倒序; 为空时按第一个允许的字段升序
+func ParseSort(sortBy string, fields ...string) (string, bool, error) {
+	if sortBy == "" {
+		return fields[0], false, nil
+	}
+	desc := strings.HasPrefix(sortBy, "-")
+	field := strings.TrimPrefix(sortBy, "-")
+	for _, allowed := range fields {
+		if field == allowed {
+			return field, desc, nil
+		}
+	}
+	return "", false, fmt.Errorf("sort只能是%s, 可加前缀-表示倒序", strings.Join(fields, "、"))
+}
+
+// 分页列表统一的返回格式, 与审计日志查询一致
+func GetPageInfo(total, page, pageSize int, items []gin.H) gin.H {
+	if items == nil {
+		items = []gin.H{}
+	}
+	return gin.H{
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"items":     items,
+	}
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xeipuuv/gojsonschema"
+	"sort"
+	"strings"
+	"zoe/basic"
+)
+
+type SchemaViolation struct {
+	// 违反的是project还是item上的schema
+	Source  string `json:"source"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// 内容不符合schema时返回全部违反项, 而不只是第一个
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for index, violation := range e.Violations {
+		messages[index] = violation.Path + ": " + violation.Message
+	}
+	return "内容不符合schema: " + strings.Join(messages, "; ")
+}
+
+// schema由用户提交, 只能引用自身内部的定义; 默认的loader会按$ref请求http(s)地址或读取本地文件
+type localSchemaLoader struct {
+	gojsonschema.JSONLoader
+}
+
+func (l localSchemaLoader) LoaderFactory() gojsonschema.JSONLoaderFactory {
+	return refusedLoaderFactory{}
+}
+
+type refusedLoaderFactory struct{}
+
+func (f refusedLoaderFactory) New(source string) gojsonschema.JSONLoader {
+	return refusedLoader{JSONLoader: gojsonschema.NewReferenceLoader(source), source: source}
+}
+
+type refusedLoader struct {
+	gojsonschema.JSONLoader
+	source string
+}
+
+func (l refusedLoader) LoadJSON() (interface{}, error) {
+	return nil, fmt.Errorf("不允许引用外部schema: %s", l.source)
+}
+
+func (l refusedLoader) LoaderFactory() gojsonschema.JSONLoaderFactory {
+	return refusedLoaderFactory{}
+}
+
+func loadSchema(schema string) (*gojsonschema.Schema, error) {
+	compiled, err := gojsonschema.NewSchema(localSchemaLoader{gojsonschema.NewStringLoader(schema)})
+	if err != nil {
+		return nil, fmt.Errorf("非法的JSON Schema: %s", err.Error())
+	}
+	return compiled, nil
+}
+
+// 检查schema本身是否合法
+func CompileSchema(schema string) error {
+	_, err := loadSchema(schema)
+	return err
+}
+
+// 按内容格式解析后用schema校验, 纯文本内容不做校验. source标明schema来源, 会带在违反项里
+func CheckSchema(schema, source, contentType, content string) error {
+	if contentType == basic.Content_Type_TEXT || contentType == "" {
+		return nil
+	}
+	compiled, err := loadSchema(schema)
+	if err != nil {
+		return err
+	}
+	value, err := parseContent(contentType, content)
+	if err != nil {
+		return err
+	}
+	result, err := compiled.Validate(gojsonschema.NewGoLoader(value))
+	if err != nil {
+		return err
+	}
+	if result.Valid() {
+		return nil
+	}
+	violations := make([]SchemaViolation, 0, len(result.Errors()))
+	for _, resultErr := range result.Errors() {
+		violations = append(violations, SchemaViolation{
+			Source:  source,
+			Path:    resultErr.Field(),
+			Message: resultErr.Description(),
+		})
+	}
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Path < violations[j].Path })
+	return &SchemaError{Violations: violations}
+}
+
+// 合并多个schema的校验结果, 非SchemaError的错误直接返回
+func MergeSchemaErrors(errs ...error) error {
+	merged := &SchemaError{}
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var schemaErr *SchemaError
+		if !errors.As(err, &schemaErr) {
+			return err
+		}
+		merged.Violations = append(merged.Violations, schemaErr.Violations...)
+	}
+	if len(merged.Violations) == 0 {
+		return nil
+	}
+	return merged
+}
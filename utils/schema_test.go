@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"zoe/basic"
+)
+
+func TestCompileSchemaRefusesExternalRef(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"type":"object"}`))
+	}))
+	defer server.Close()
+	cases := []struct {
+		name   string
+		schema string
+	}{
+		{"http", `{"$ref":"` + server.URL + `/schema.json"}`},
+		{"http fragment", `{"properties":{"a":{"$ref":"` + server.URL + `/schema.json#/definitions/a"}}}`},
+		{"file", `{"$ref":"file:///etc/passwd"}`},
+		{"relative", `{"$id":"` + server.URL + `/root.json","$ref":"other.json"}`},
+		{"nested", `{"definitions":{"a":{"$ref":"file:///etc/hosts"}},"properties":{"a":{"$ref":"#/definitions/a"}}}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := CompileSchema(tc.schema); err == nil {
+				t.Errorf("CompileSchema() should refuse %s", tc.schema)
+			}
+			if err := CheckSchema(tc.schema, "item", basic.Content_Type_JSON, `{"a":1}`); err == nil {
+				t.Errorf("CheckSchema() should refuse %s", tc.schema)
+			}
+		})
+	}
+	if requests != 0 {
+		t.Errorf("schema loading sent %d requests", requests)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	schema := `{
+		"definitions": {"port": {"type": "integer", "minimum": 1, "maximum": 65535}},
+		"type": "object",
+		"required": ["port"],
+		"properties": {"port": {"$ref": "#/definitions/port"}, "host": {"type": "string"}}
+	}`
+	cases := []struct {
+		name        string
+		contentType string
+		content     string
+		violations  []string
+	}{
+		{"valid json", basic.Content_Type_JSON, `{"port":80}`, nil},
+		{"valid yaml", basic.Content_Type_YAML, "port: 80\nhost: a", nil},
+		{"text is not checked", basic.Content_Type_TEXT, "anything", nil},
+		{"local ref", basic.Content_Type_JSON, `{"port":0}`, []string{"port"}},
+		{"all violations", basic.Content_Type_JSON, `{"host":1}`, []string{"(root)", "host"}},
+	}
+	if err := CompileSchema(schema); err != nil {
+		t.Fatalf("CompileSchema() error = %v", err)
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckSchema(schema, "item", tc.contentType, tc.content)
+			if tc.violations == nil {
+				if err != nil {
+					t.Errorf("CheckSchema() error = %v", err)
+				}
+				return
+			}
+			schemaErr, ok := err.(*SchemaError)
+			if !ok {
+				t.Fatalf("CheckSchema() error = %v, want *SchemaError", err)
+			}
+			var paths []string
+			for _, violation := range schemaErr.Violations {
+				paths = append(paths, violation.Path)
+			}
+			if strings.Join(paths, ",") != strings.Join(tc.violations, ",") {
+				t.Errorf("violations = %v, want %v", paths, tc.violations)
+			}
+		})
+	}
+}
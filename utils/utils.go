@@ -1,42 +1,83 @@
 package utils
 
 import (
-	"database/sql"
+	"encoding/json"
+	"errors"
 	"github.com/gin-gonic/gin"
-	"zoe/dao/db"
+	"zoe/basic"
 	"zoe/model"
 )
 
-func GetUser(conn *sql.Tx, userHash string) (*model.User, error) {
-	user, err := db.GetUserByUserHash(conn, userHash)
-	if err != nil {
-		return nil, err
+func ParsePrivilegeType(pType string) (int, error) {
+	if pType == "modifier" {
+		return basic.Privilege_Type_MODIFIER, nil
+	} else if pType == "viewer" {
+		return basic.Privilege_Type_VIEWER, nil
+	} else if pType == "puller" {
+		return basic.Privilege_Type_PULLER, nil
 	}
-	return user, nil
+	return 0, errors.New("非法的授权类型")
+}
+
+// private为"true"时私有, "false"时公开, 其余取值沿用私有
+func ParseVisibility(private string) int {
+	if private == "false" {
+		return 1
+	}
+	return 0
+}
+
+func ParseResourceType(resType string) (int, error) {
+	if resType == "org" {
+		return basic.Resource_Type_ORG, nil
+	} else if resType == "project" {
+		return basic.Resource_Type_PROJECT, nil
+	} else if resType == "item" {
+		return basic.Resource_Type_ITEM, nil
+	}
+	return 0, errors.New("非法的资源类型")
+}
+
+func FormatResourceType(typeId int) string {
+	if typeId == basic.Resource_Type_ORG {
+		return "org"
+	} else if typeId == basic.Resource_Type_PROJECT {
+		return "project"
+	} else if typeId == basic.Resource_Type_ITEM {
+		return "item"
+	}
+	return "unknown_resource_type"
+}
+
+func FormatPrivilegeType(typeId int) string {
+	if typeId == basic.Privilege_Type_PULLER {
+		return "puller"
+	} else if typeId == basic.Privilege_Type_VIEWER {
+		return "viewer"
+	} else if typeId == basic.Privilege_Type_MODIFIER {
+		return "modifier"
+	}
+	return "unknown_privilege_type"
 }
 
 func GetPrivilegeUserInfo(privileges *[]model.Privilege, users *[]model.User) []gin.H {
 	if privileges == nil || len(*privileges) == 0 {
 		return nil
 	}
+	userNames := make(map[int]string)
+	if users != nil {
+		for _, user := range *users {
+			userNames[user.Id] = user.Name
+		}
+	}
 	resData := make([]gin.H, len(*privileges))
 	for index := range resData {
-		var pType string
-		typeId := (*privileges)[index].PrivilegeType
-		if typeId == 0 {
-			pType = "puller"
-		} else if typeId == 1 {
-			pType = "viewer"
-		} else if typeId == 2 {
-			pType = "modifier"
-		} else {
-			pType = "unknown_privilege_type"
-		}
 		resData[index] = gin.H{
-			"id":        (*privileges)[index].Id,
-			"type":      pType,
-			"user_id":   (*users)[index].Id,
-			"user_name": (*users)[index].Name,
+			"id":          (*privileges)[index].Id,
+			"type":        FormatPrivilegeType((*privileges)[index].PrivilegeType),
+			"user_id":     (*privileges)[index].UserId,
+			"user_name":   userNames[(*privileges)[index].UserId],
+			"environment": (*privileges)[index].Environment,
 		}
 	}
 	return resData
@@ -89,3 +130,138 @@ func GetOrgInfo(projectInfo []gin.H, privilegeInfo []gin.H, org *model.Org) gin.
 	}
 	return orgInfo
 }
+
+func GetItemInfo(item *model.Item) gin.H {
+	var visibility string
+	if (*item).Visibility == 0 {
+		visibility = "private"
+	} else if (*item).Visibility == 1 {
+		visibility = "public"
+	} else {
+		visibility = "unknown_visibility_type"
+	}
+	return gin.H{
+		"id":                 (*item).Id,
+		"name":               (*item).Name,
+		"parent_id":          (*item).ParentId,
+		"visibility":         visibility,
+		"content":            (*item).Content,
+		"current_version_id": (*item).CurrentVersionId,
+		"environment":        (*item).Environment,
+		"content_type":       (*item).ContentType,
+		"encrypted":          (*item).Encrypted == 1,
+	}
+}
+
+// item在缓存和变更通知中的标识, 默认环境下即item名, 其余环境为"item名@环境名"
+func GetItemKey(name, environment string) string {
+	if environment == "" {
+		return name
+	}
+	return name + "@" + environment
+}
+
+func GetVersionInfo(versions *[]model.Version, users *[]model.User) []gin.H {
+	if versions == nil || len(*versions) == 0 {
+		return nil
+	}
+	userNames := make(map[int]string)
+	if users != nil {
+		for _, user := range *users {
+			userNames[user.Id] = user.Name
+		}
+	}
+	resData := make([]gin.H, len(*versions))
+	for index, version := range *versions {
+		var visibility string
+		if version.Visibility == 0 {
+			visibility = "private"
+		} else if version.Visibility == 1 {
+			visibility = "public"
+		} else {
+			visibility = "unknown_visibility_type"
+		}
+		resData[index] = gin.H{
+			"id":            version.Id,
+			"resource_id":   version.ResourceId,
+			"resource_type": FormatResourceType(version.ResourceType),
+			"resource_name": version.ResourceName,
+			"visibility":    visibility,
+			"content":       version.Content,
+			"encrypted":     version.Encrypted == 1,
+			"user_id":       version.UserId,
+			"user_name":     userNames[version.UserId],
+			"rollback_from": version.RollbackFrom,
+			"created_at":    version.CreateAt,
+		}
+	}
+	return resData
+}
+
+func GetItemsInfo(items *[]model.Item) []gin.H {
+	if items == nil || len(*items) == 0 {
+		return nil
+	}
+	resData := make([]gin.H, len(*items))
+	for index, item := range *items {
+		var visibility string
+		if item.Visibility == 0 {
+			visibility = "private"
+		} else if item.Visibility == 1 {
+			visibility = "public"
+		} else {
+			visibility = "unknown_visibility_type"
+		}
+		resData[index] = gin.H{
+			"id":                 item.Id,
+			"name":               item.Name,
+			"parent_id":          item.ParentId,
+			"visibility":         visibility,
+			"current_version_id": item.CurrentVersionId,
+			"environment":        item.Environment,
+			"content_type":       item.ContentType,
+			"encrypted":          item.Encrypted == 1,
+		}
+	}
+	return resData
+}
+
+func GetAuditInfo(audits *[]model.Audit, users *[]model.User) []gin.H {
+	if audits == nil || len(*audits) == 0 {
+		return []gin.H{}
+	}
+	userNames := make(map[int]string)
+	if users != nil {
+		for _, user := range *users {
+			userNames[user.Id] = user.Name
+		}
+	}
+	resData := make([]gin.H, len(*audits))
+	for index, audit := range *audits {
+		info := gin.H{
+			"id":             audit.Id,
+			"org_id":         audit.OrgId,
+			"user_id":        audit.UserId,
+			"user_name":      userNames[audit.UserId],
+			"action":         audit.Action,
+			"resource_type":  FormatResourceType(audit.ResourceType),
+			"resource_id":    audit.ResourceId,
+			"resource_name":  audit.ResourceName,
+			"target_user_id": audit.TargetUserId,
+			"before":         nil,
+			"after":          nil,
+			"created_at":     audit.CreateAt,
+		}
+		if audit.TargetUserId != 0 {
+			info["target_user_name"] = userNames[audit.TargetUserId]
+		}
+		if audit.Before != "" {
+			info["before"] = json.RawMessage(audit.Before)
+		}
+		if audit.After != "" {
+			info["after"] = json.RawMessage(audit.After)
+		}
+		resData[index] = info
+	}
+	return resData
+}